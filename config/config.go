@@ -0,0 +1,601 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/asynccnu/bff/pkg/cryptox"
+	"github.com/asynccnu/bff/pkg/grpcx"
+	"github.com/asynccnu/bff/pkg/jwtx"
+	"github.com/asynccnu/bff/web/rbac/perm"
+	"github.com/spf13/viper"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EnvPrefix 环境变量覆盖配置时使用的前缀,key 中的 . 换成 _,例如 jwt.jwtKey 对应 BFF_JWT_JWTKEY
+const EnvPrefix = "BFF"
+
+// minJwtKeyLen HS256 的密钥至少要和摘要一样长,太短的密钥可以被暴力破解
+const minJwtKeyLen = 32
+
+// GrpcClients 所有必须配置的 grpc 下游,新增下游的时候记得加在这里
+var GrpcClients = []string{
+	"banner", "calendar", "card", "ccnu", "classService", "classlist", "counter", "department",
+	"elecprice", "feed", "feedbackHelp", "grade", "infoSum", "static", "user", "website",
+}
+
+// Config 启动时需要的全部配置,在 main 里统一加载并校验,ioc 中不再各自解析
+type Config struct {
+	HTTP       HTTPConfig       `yaml:"http"`
+	Redis      RedisConfig      `yaml:"redis"`
+	Kafka      KafkaConfig      `yaml:"kafka"`
+	Etcd       EtcdConfig       `yaml:"etcd"`
+	Grpc       GrpcConfig       `yaml:"grpc"`
+	JWT        JWTConfig        `yaml:"jwt"`
+	OSS        OSSConfig        `yaml:"oss"`
+	Log        LogConfig        `yaml:"log"`
+	Prometheus PrometheusConfig `yaml:"prometheus"`
+	Health     HealthConfig     `yaml:"health"`
+	Deadline   DeadlineConfig   `yaml:"deadline"`
+	RBAC       RBACConfig       `yaml:"rbac"`
+	Audit      AuditConfig      `yaml:"audit"`
+
+	// Runtime 可以热更新的那部分配置在启动时的值
+	Runtime *RuntimeConfig `mapstructure:"-" yaml:"-"`
+}
+
+type HTTPConfig struct {
+	Addr              string        `yaml:"addr"`
+	ShutdownTimeout   time.Duration `yaml:"shutdownTimeout"`   // 优雅退出时等待请求处理完成的最长时间
+	ReadTimeout       time.Duration `yaml:"readTimeout"`       // 读取整个请求(包括 body)的超时时间
+	ReadHeaderTimeout time.Duration `yaml:"readHeaderTimeout"` // 读取请求头的超时时间,防止慢速攻击
+	WriteTimeout      time.Duration `yaml:"writeTimeout"`      // 写响应的超时时间,要比所有路由的超时预算都长
+	IdleTimeout       time.Duration `yaml:"idleTimeout"`       // keep-alive 连接的空闲时间
+	MaxHeaderBytes    int           `yaml:"maxHeaderBytes"`    // 请求头的最大字节数
+	TLS               HTTPTLSConfig `yaml:"tls"`
+	H2C               bool          `yaml:"h2c"`            // 不开启 TLS 时支持明文的 HTTP/2,只应该在可信的代理后面开启
+	TrustedProxies    []string      `yaml:"trustedProxies"` // 可信代理的网段,用来从 X-Forwarded-For 中取客户端 IP
+}
+
+// HTTPTLSConfig 开启后使用 HTTPS,并自动支持 HTTP/2,证书文件被替换后新的连接会使用新证书
+type HTTPTLSConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+}
+
+type RedisConfig struct {
+	Addr     string `yaml:"addr"`
+	Password string `yaml:"password" secret:"true"`
+}
+
+type KafkaConfig struct {
+	Addrs []string `yaml:"addrs"`
+}
+
+type EtcdConfig struct {
+	Endpoints   []string      `yaml:"endpoints"`
+	Username    string        `yaml:"username"`
+	Password    string        `yaml:"password" secret:"true"`
+	DialTimeout time.Duration `yaml:"dialTimeout"`
+}
+
+type GrpcConfig struct {
+	// TLS 所有下游默认使用的 TLS 配置,单个下游可以用自己的 tls 覆盖,或者用 insecure 强制使用明文
+	TLS    grpcx.TLSConfig               `yaml:"tls"`
+	Client map[string]grpcx.ClientConfig `yaml:"client"`
+}
+
+// Lookup 按名字取某个下游的配置,viper 会把 map 的 key 全部转成小写,所以这里不区分大小写
+func (c GrpcConfig) Lookup(name string) grpcx.ClientConfig {
+	client := c.Client[strings.ToLower(name)]
+	if client.TLS.IsZero() {
+		client.TLS = c.TLS
+	}
+	return client
+}
+
+type JWTConfig struct {
+	// Algorithm 签名算法,HS256(默认)、RS256 或 EdDSA
+	// HS256 使用 jwtKey/refreshKey;非对称算法使用 signingKeys,这时 jwtKey/refreshKey 可选,配置了就继续接受切换之前签发的 token
+	Algorithm   string            `yaml:"algorithm"`
+	JwtKey      string            `yaml:"jwtKey" secret:"true"`
+	RefreshKey  string            `yaml:"refreshKey" secret:"true"`
+	SigningKeys SigningKeysConfig `yaml:"signingKeys"`
+	Credential  CredentialConfig  `yaml:"credential"`
+	MaxDevices  int               `yaml:"maxDevices"` // 每个学号同时登录的设备数上限,超出时最久未活跃的设备会被踢下线,0 表示不限制
+	// DeviceBinding token 和设备的绑定方式
+	DeviceBinding DeviceBindingConfig `yaml:"deviceBinding"`
+	// Cookie 网页端使用的 cookie 模式
+	Cookie CookieConfig `yaml:"cookie"`
+}
+
+// token 和设备的绑定方式
+const (
+	DeviceBindingNone      = "none"       // 不绑定
+	DeviceBindingUserAgent = "user_agent" // User-Agent 必须和登录时完全一致,应用或者系统升级之后需要重新登录
+	DeviceBindingDevice    = "device"     // X-Device-Id 必须和登录时一致,登录时上报了设备公钥的还要校验签名
+	DeviceBindingSigned    = "signed"     // 同 device,但是登录时必须上报设备公钥
+)
+
+// DeviceBindingConfig 按客户端类型(X-Client-Type 请求头,比如 app、web)选择绑定方式
+type DeviceBindingConfig struct {
+	DefaultClientType string            `yaml:"defaultClientType"` // 请求没有带 X-Client-Type 时当作哪种客户端
+	Policies          map[string]string `yaml:"policies"`          // 客户端类型 -> 绑定方式,没有配置的类型使用 user_agent
+}
+
+// CookieConfig 开启 cookie 模式的客户端不再通过响应头拿 token,而是由服务端写进 HttpOnly 的 cookie,
+// 浏览器自动携带,写操作需要把 csrf cookie 的值放进 X-CSRF-Token 请求头(双重提交)
+type CookieConfig struct {
+	ClientTypes []string `yaml:"clientTypes"` // 使用 cookie 模式的客户端类型(X-Client-Type),请求头 X-Auth-Mode 可以覆盖
+	Domain      string   `yaml:"domain"`      // 为空表示只发给当前域名
+	SameSite    string   `yaml:"sameSite"`    // strict、lax 或 none
+	RefreshPath string   `yaml:"refreshPath"` // 刷新令牌的 cookie 只发给刷新接口
+}
+
+// SigningKeysConfig 非对称签名的密钥,公钥会通过 /.well-known/jwks.json 公开
+// 轮换步骤:先在所有实例上加入新密钥,再把 activeKid 切过去,旧密钥改成只配置公钥,保留到刷新令牌的有效期(7 天)结束后删除
+type SigningKeysConfig struct {
+	ActiveKid string             `yaml:"activeKid"`
+	Keys      []SigningKeyConfig `yaml:"keys"`
+}
+
+type SigningKeyConfig struct {
+	Kid            string `yaml:"kid"`
+	PrivateKeyFile string `yaml:"privateKeyFile"` // PEM 格式的私钥
+	PublicKeyFile  string `yaml:"publicKeyFile"`  // PEM 格式的公钥,只用于验签的旧密钥只需要配置这一项
+}
+
+// KeySets 创建签发短 token 和长 token 使用的密钥
+// HS256 下两种 token 用各自的密钥;非对称算法下共用 signingKeys,靠 header 里的 typ 区分,防止长 token 被当成短 token 使用
+func (c JWTConfig) KeySets() (access *jwtx.KeySet, refresh *jwtx.KeySet, err error) {
+	if c.Algorithm == "" || c.Algorithm == jwtx.AlgHS256 {
+		return jwtx.NewHMACKeySet([]byte(c.JwtKey)), jwtx.NewHMACKeySet([]byte(c.RefreshKey)), nil
+	}
+
+	keys := make([]jwtx.Key, 0, len(c.SigningKeys.Keys))
+	var errs []error
+	for i, k := range c.SigningKeys.Keys {
+		key := jwtx.Key{Kid: k.Kid}
+		if k.PrivateKeyFile != "" {
+			if key.PrivateKey, err = os.ReadFile(k.PrivateKeyFile); err != nil {
+				errs = append(errs, fmt.Errorf("keys[%d]: %w", i, err))
+			}
+		}
+		if k.PublicKeyFile != "" {
+			if key.PublicKey, err = os.ReadFile(k.PublicKeyFile); err != nil {
+				errs = append(errs, fmt.Errorf("keys[%d]: %w", i, err))
+			}
+		}
+		keys = append(keys, key)
+	}
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+	if access, err = jwtx.NewKeySet(c.Algorithm, c.SigningKeys.ActiveKid, keys); err != nil {
+		return nil, nil, err
+	}
+	refresh, _ = jwtx.NewKeySet(c.Algorithm, c.SigningKeys.ActiveKid, keys)
+	if c.JwtKey != "" {
+		access.WithHMAC([]byte(c.JwtKey))
+	}
+	if c.RefreshKey != "" {
+		refresh.WithHMAC([]byte(c.RefreshKey))
+	}
+	return access, refresh, nil
+}
+
+// CredentialConfig 服务端保存学号密码时使用的加密密钥
+// keys 的 key 是密钥 id(viper 会转成小写),值是 base64 编码的 32 字节密钥,新数据总是用 activeKey 加密,
+// 轮换时先加入新密钥并切换 activeKey,旧密钥至少保留到刷新令牌的有效期(7 天)结束
+type CredentialConfig struct {
+	ActiveKey string            `yaml:"activeKey"`
+	Keys      map[string]string `yaml:"keys" secret:"true"`
+}
+
+// Keyring 根据配置创建密钥环
+func (c CredentialConfig) Keyring() (*cryptox.Keyring, error) {
+	return cryptox.NewKeyring(strings.ToLower(c.ActiveKey), c.Keys)
+}
+
+type OSSConfig struct {
+	AccessKey  string `yaml:"accessKey" secret:"true"`
+	SecretKey  string `yaml:"secretKey" secret:"true"`
+	BucketName string `yaml:"bucketName"`
+	DomainName string `yaml:"domainName"` // CDN 域名
+}
+
+type LogConfig struct {
+	Path       string `yaml:"path"`
+	MaxSize    int    `yaml:"maxSize"`    // 每个日志文件的最大大小，单位：MB
+	MaxBackups int    `yaml:"maxBackups"` // 保留旧日志文件的最大个数
+	MaxAge     int    `yaml:"maxAge"`     // 保留旧日志文件的最大天数
+	Compress   int    `yaml:"compress"`   // 是否压缩旧的日志文件
+	Level      string `yaml:"level"`      // 支持热更新,由 RuntimeConfig 负责校验
+}
+
+type MetricConfig struct {
+	Name string `yaml:"name"`
+	Help string `yaml:"help"`
+}
+
+type PrometheusConfig struct {
+	Namespace           string       `yaml:"namespace"` //项目名称
+	RouterCounter       MetricConfig `yaml:"routerCounter"`
+	ActiveConnections   MetricConfig `yaml:"activeConnections"`
+	DurationTime        MetricConfig `yaml:"durationTime"`
+	ConfigReloadCounter MetricConfig `yaml:"configReloadCounter"`
+	DegradedAuthCounter MetricConfig `yaml:"degradedAuthCounter"`
+}
+
+type HealthConfig struct {
+	Timeout     time.Duration `yaml:"timeout"`     // 单个依赖的探测超时时间
+	NonCritical []string      `yaml:"nonCritical"` // 非关键依赖,探测失败不影响就绪状态
+}
+
+// DeadlineConfig 每个请求的时间预算,请求的 context 会带上这个 deadline,grpc 调用会一直沿用下去。
+// 预算只会缩短下游调用的超时,不会延长,grpc.client 里没有配置 timeout 的下游单次调用仍然最多 2s
+type DeadlineConfig struct {
+	Default time.Duration   `yaml:"default"` // 没有匹配到任何规则时使用的预算
+	Routes  []RouteDeadline `yaml:"routes"`  // 按顺序匹配,第一个匹配上的生效
+}
+
+type RouteDeadline struct {
+	Method  string            `yaml:"method"` // 为空表示匹配所有方法
+	Path    string            `yaml:"path"`   // gin 注册的完整路由,例如 /api/v1/class/get
+	Query   map[string]string `yaml:"query"`  // 查询参数的值需要完全相等才算匹配,参数名不区分大小写(viper 会把 key 转成小写)
+	Timeout time.Duration     `yaml:"timeout"`
+}
+
+// RBACConfig 角色和授权的初始值,启动时写入 redis 中还不存在的角色和学号,之后以 redis 为准,通过管理接口修改
+// runtime 配置里的 administrators 不受 RBAC 管理,始终拥有全部权限
+type RBACConfig struct {
+	Roles       map[string][]string `yaml:"roles"`       // 角色 -> 权限,例如 editor: ["banner:write", "feed:publish"],"*" 表示全部权限
+	Assignments map[string][]string `yaml:"assignments"` // 学号 -> 角色
+}
+
+// 审计日志的写入位置
+const (
+	AuditSinkRedis = "redis" // 写入 redis stream,可以通过管理接口查询
+	AuditSinkKafka = "kafka" // 写入 kafka,由下游消费存档,管理接口查不到
+	AuditSinkFile  = "file"  // 按行写入本地文件,可以通过管理接口查询,多实例部署时只能查到本实例的
+)
+
+// AuditConfig 管理员写操作的审计日志
+type AuditConfig struct {
+	Sink   string `yaml:"sink"`   // redis、kafka 或 file
+	MaxLen int64  `yaml:"maxLen"` // redis stream 保留的最大条数,超出后删除最旧的
+	Topic  string `yaml:"topic"`  // kafka 的 topic
+	Path   string `yaml:"path"`   // file 的路径
+}
+
+// BindEnv 让 BFF_ 开头的环境变量可以覆盖配置文件,容器里的密钥就不用写进 config.yaml 了
+func BindEnv(v *viper.Viper) {
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.AutomaticEnv()
+	// AutomaticEnv 只对配置文件里已经出现的 key 生效,
+	// 这里把所有已知的 key 都绑定一遍,配置文件里完全不写也能通过环境变量注入
+	for _, key := range envKeys(reflect.TypeOf(Config{}), "") {
+		_ = v.BindEnv(key)
+	}
+	for _, name := range GrpcClients {
+		for _, key := range envKeys(reflect.TypeOf(grpcx.ClientConfig{}), "grpc.client."+name) {
+			_ = v.BindEnv(key)
+		}
+	}
+	// map 的 key 事先不知道,只能从环境变量的名字里取出来,配置文件里没有的密钥 id 也能注入
+	bindEnvMap(v, "jwt.credential.keys")
+}
+
+// bindEnvMap 把 BFF_<KEY>_<ID> 形式的环境变量绑定到 map 字段 key 下的 id,
+// 例如 BFF_JWT_CREDENTIAL_KEYS_K2 对应 jwt.credential.keys.k2
+func bindEnvMap(v *viper.Viper, key string) {
+	prefix := EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_")) + "_"
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if id, ok := strings.CutPrefix(name, prefix); ok && id != "" {
+			_ = v.BindEnv(key+"."+strings.ToLower(id), name)
+		}
+	}
+}
+
+// envKeys 按照 yaml tag 列出结构体中所有叶子字段的 key,map 类型的字段跳过
+func envKeys(t reflect.Type, prefix string) []string {
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := f.Tag.Get("yaml")
+		if name == "" || name == "-" {
+			continue
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		switch {
+		case f.Type.Kind() == reflect.Struct && f.Type != reflect.TypeOf(time.Duration(0)):
+			keys = append(keys, envKeys(f.Type, name)...)
+		case f.Type.Kind() == reflect.Map:
+		default:
+			keys = append(keys, name)
+		}
+	}
+	return keys
+}
+
+func setDefaults(v *viper.Viper) {
+	v.SetDefault("http.shutdownTimeout", 30*time.Second)
+	v.SetDefault("http.readTimeout", 30*time.Second)
+	v.SetDefault("http.readHeaderTimeout", 5*time.Second)
+	v.SetDefault("http.writeTimeout", 60*time.Second)
+	v.SetDefault("http.idleTimeout", 120*time.Second)
+	v.SetDefault("http.maxHeaderBytes", 1<<20)
+	v.SetDefault("etcd.dialTimeout", 5*time.Second)
+	v.SetDefault("jwt.maxDevices", 5)
+	v.SetDefault("jwt.deviceBinding.defaultClientType", "app")
+	v.SetDefault("jwt.cookie.sameSite", "strict")
+	v.SetDefault("jwt.cookie.refreshPath", "/api/v1/users/refresh_token")
+	v.SetDefault("health.timeout", time.Second)
+	v.SetDefault("deadline.default", 30*time.Second)
+	v.SetDefault("audit.sink", AuditSinkRedis)
+	v.SetDefault("audit.maxLen", 100000)
+	v.SetDefault("audit.topic", "bff_audit")
+	v.SetDefault("audit.path", "./logs/audit.log")
+	v.SetDefault("prometheus.configReloadCounter.name", "config_reload_total")
+	v.SetDefault("prometheus.degradedAuthCounter.name", "auth_degraded_total")
+}
+
+// Load 解析并校验全部配置,所有的问题会一次性返回,方便一次改完
+func Load(v *viper.Viper) (*Config, error) {
+	setDefaults(v)
+
+	var cfg Config
+	if err := v.Unmarshal(&cfg); err != nil {
+		return nil, fmt.Errorf("解析配置失败: %w", err)
+	}
+
+	var errs []error
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	rt, err := LoadRuntimeConfig(v)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	cfg.Runtime = rt
+	return &cfg, nil
+}
+
+// Validate 校验启动必需的配置项,不会在第一个错误处停下
+func (c *Config) Validate() error {
+	var errs []error
+	required := func(key, val string) {
+		if strings.TrimSpace(val) == "" {
+			errs = append(errs, fmt.Errorf("%s: 不能为空", key))
+		}
+	}
+
+	required("http.addr", c.HTTP.Addr)
+	required("redis.addr", c.Redis.Addr)
+
+	// 只有用到注册中心的时候才需要 etcd
+	needsEtcd := false
+	for _, client := range c.Grpc.Client {
+		needsEtcd = needsEtcd || client.NeedsDiscovery()
+	}
+	if needsEtcd && len(c.Etcd.Endpoints) == 0 {
+		errs = append(errs, errors.New("etcd.endpoints: 存在 discovery:/// 形式的下游,至少需要一个 endpoint"))
+	}
+
+	for _, name := range GrpcClients {
+		if _, ok := c.Grpc.Client[strings.ToLower(name)]; !ok {
+			errs = append(errs, fmt.Errorf("grpc.client.%s: 缺少该下游的配置", name))
+		}
+	}
+	if c.Grpc.TLS.Enabled {
+		if err := c.Grpc.TLS.Validate("grpc.tls"); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	// map 的遍历顺序是随机的,排个序让每次输出的错误顺序一致
+	names := make([]string, 0, len(c.Grpc.Client))
+	for name := range c.Grpc.Client {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := c.Grpc.Lookup(name).Validate("grpc.client." + name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	// 非对称签名时 HS256 的密钥只用来兼容旧 token,可以不配置
+	hmac := c.JWT.Algorithm == "" || c.JWT.Algorithm == jwtx.AlgHS256
+	if (hmac || c.JWT.JwtKey != "") && len(c.JWT.JwtKey) < minJwtKeyLen {
+		errs = append(errs, fmt.Errorf("jwt.jwtKey: 长度至少为 %d 字节", minJwtKeyLen))
+	}
+	if (hmac || c.JWT.RefreshKey != "") && len(c.JWT.RefreshKey) < minJwtKeyLen {
+		errs = append(errs, fmt.Errorf("jwt.refreshKey: 长度至少为 %d 字节", minJwtKeyLen))
+	}
+	if c.JWT.JwtKey != "" && c.JWT.JwtKey == c.JWT.RefreshKey {
+		errs = append(errs, errors.New("jwt.refreshKey: 不能和 jwtKey 相同"))
+	}
+	switch c.JWT.Algorithm {
+	case "", jwtx.AlgHS256, jwtx.AlgRS256, jwtx.AlgEdDSA:
+		if _, _, err := c.JWT.KeySets(); err != nil {
+			errs = append(errs, fmt.Errorf("jwt.signingKeys: %w", err))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("jwt.algorithm: 只支持 %s、%s 和 %s", jwtx.AlgHS256, jwtx.AlgRS256, jwtx.AlgEdDSA))
+	}
+	for _, clientType := range sortedKeys(c.JWT.DeviceBinding.Policies) {
+		switch policy := c.JWT.DeviceBinding.Policies[clientType]; policy {
+		case DeviceBindingNone, DeviceBindingUserAgent, DeviceBindingDevice, DeviceBindingSigned:
+		default:
+			errs = append(errs, fmt.Errorf("jwt.deviceBinding.policies.%s: 不支持的绑定方式 %q", clientType, policy))
+		}
+	}
+	switch c.JWT.Cookie.SameSite {
+	case "strict", "lax", "none":
+	default:
+		errs = append(errs, fmt.Errorf("jwt.cookie.sameSite: 只支持 strict、lax 和 none,不支持 %q", c.JWT.Cookie.SameSite))
+	}
+	if !strings.HasPrefix(c.JWT.Cookie.RefreshPath, "/") {
+		errs = append(errs, errors.New("jwt.cookie.refreshPath: 必须以 / 开头"))
+	}
+	if c.JWT.MaxDevices < 0 {
+		errs = append(errs, errors.New("jwt.maxDevices: 不能为负数"))
+	}
+	if _, err := c.JWT.Credential.Keyring(); err != nil {
+		errs = append(errs, fmt.Errorf("jwt.credential: %w", err))
+	}
+
+	required("oss.accessKey", c.OSS.AccessKey)
+	required("oss.secretKey", c.OSS.SecretKey)
+	required("oss.bucketName", c.OSS.BucketName)
+	required("oss.domainName", c.OSS.DomainName)
+
+	if err := checkLogPath(c.Log.Path); err != nil {
+		errs = append(errs, fmt.Errorf("log.path: %w", err))
+	}
+	if c.Log.MaxSize < 0 || c.Log.MaxBackups < 0 || c.Log.MaxAge < 0 {
+		errs = append(errs, errors.New("log: maxSize、maxBackups、maxAge 不能为负数"))
+	}
+
+	required("prometheus.namespace", c.Prometheus.Namespace)
+	required("prometheus.routerCounter.name", c.Prometheus.RouterCounter.Name)
+	required("prometheus.activeConnections.name", c.Prometheus.ActiveConnections.Name)
+	required("prometheus.durationTime.name", c.Prometheus.DurationTime.Name)
+
+	if c.HTTP.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("http.shutdownTimeout: 必须大于 0"))
+	}
+	if c.HTTP.ReadTimeout < 0 || c.HTTP.ReadHeaderTimeout < 0 || c.HTTP.WriteTimeout < 0 || c.HTTP.IdleTimeout < 0 {
+		errs = append(errs, errors.New("http: readTimeout、readHeaderTimeout、writeTimeout、idleTimeout 不能为负数"))
+	}
+	if c.HTTP.MaxHeaderBytes < 0 {
+		errs = append(errs, errors.New("http.maxHeaderBytes: 不能为负数"))
+	}
+	if c.HTTP.TLS.Enabled {
+		for _, f := range []struct{ key, file string }{{"certFile", c.HTTP.TLS.CertFile}, {"keyFile", c.HTTP.TLS.KeyFile}} {
+			if f.file == "" {
+				errs = append(errs, fmt.Errorf("http.tls.%s: 开启 TLS 时不能为空", f.key))
+			} else if _, err := os.Stat(f.file); err != nil {
+				errs = append(errs, fmt.Errorf("http.tls.%s: %w", f.key, err))
+			}
+		}
+		if c.HTTP.H2C {
+			errs = append(errs, errors.New("http.h2c: 开启 TLS 时已经支持 HTTP/2,不能同时开启 h2c"))
+		}
+	}
+	for _, proxy := range c.HTTP.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			errs = append(errs, fmt.Errorf("http.trustedProxies: 非法的地址 %q", proxy))
+		}
+	}
+	if c.Health.Timeout <= 0 {
+		errs = append(errs, errors.New("health.timeout: 必须大于 0"))
+	}
+	if c.Deadline.Default <= 0 {
+		errs = append(errs, errors.New("deadline.default: 必须大于 0"))
+	}
+	for _, role := range sortedKeys(c.RBAC.Roles) {
+		if !perm.ValidRole(role) {
+			errs = append(errs, fmt.Errorf("rbac.roles.%s: 非法的角色名,只能包含小写字母、数字、_ 和 -", role))
+		}
+		for _, p := range c.RBAC.Roles[role] {
+			if !perm.Known(p) {
+				errs = append(errs, fmt.Errorf("rbac.roles.%s: 未知的权限 %q", role, p))
+			}
+		}
+	}
+	for _, studentId := range sortedKeys(c.RBAC.Assignments) {
+		for _, role := range c.RBAC.Assignments[studentId] {
+			if _, ok := c.RBAC.Roles[role]; !ok {
+				errs = append(errs, fmt.Errorf("rbac.assignments.%s: 角色 %q 没有在 rbac.roles 中定义", studentId, role))
+			}
+		}
+	}
+	switch c.Audit.Sink {
+	case AuditSinkRedis:
+		if c.Audit.MaxLen <= 0 {
+			errs = append(errs, errors.New("audit.maxLen: 必须大于 0"))
+		}
+	case AuditSinkKafka:
+		if len(c.Kafka.Addrs) == 0 {
+			errs = append(errs, errors.New("kafka.addrs: audit.sink 为 kafka 时至少需要一个地址"))
+		}
+		required("audit.topic", c.Audit.Topic)
+	case AuditSinkFile:
+		if err := checkLogPath(c.Audit.Path); err != nil {
+			errs = append(errs, fmt.Errorf("audit.path: %w", err))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("audit.sink: 只支持 %s、%s 和 %s", AuditSinkRedis, AuditSinkKafka, AuditSinkFile))
+	}
+
+	// 写超时比预算还短的话,请求还在正常处理响应就已经被掐断了
+	if w := c.HTTP.WriteTimeout; w > 0 && c.Deadline.Default >= w {
+		errs = append(errs, fmt.Errorf("deadline.default: 必须小于 http.writeTimeout(%s)", w))
+	}
+	for i, route := range c.Deadline.Routes {
+		if w := c.HTTP.WriteTimeout; w > 0 && route.Timeout >= w {
+			errs = append(errs, fmt.Errorf("deadline.routes[%d].timeout: 必须小于 http.writeTimeout(%s)", i, w))
+		}
+		if !strings.HasPrefix(route.Path, "/") {
+			errs = append(errs, fmt.Errorf("deadline.routes[%d].path: 必须以 / 开头", i))
+		}
+		if route.Timeout <= 0 {
+			errs = append(errs, fmt.Errorf("deadline.routes[%d].timeout: 必须大于 0", i))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// sortedKeys map 的遍历顺序是随机的,排序之后报错的顺序才稳定
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// checkLogPath 日志目录不存在时 lumberjack 会自己创建,这里只检查路径本身是否可用
+func checkLogPath(path string) error {
+	if strings.TrimSpace(path) == "" {
+		return errors.New("不能为空")
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return fmt.Errorf("%s 是一个目录", path)
+	}
+	// 往上找到第一个存在的目录,确保它确实是目录
+	dir := filepath.Dir(path)
+	for {
+		info, err := os.Stat(dir)
+		if err == nil {
+			if !info.IsDir() {
+				return fmt.Errorf("%s 不是目录", dir)
+			}
+			return nil
+		}
+		if !os.IsNotExist(err) {
+			return err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil
+		}
+		dir = parent
+	}
+}
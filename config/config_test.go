@@ -0,0 +1,252 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const validYaml = `
+http:
+  addr: ":8080"
+redis:
+  addr: "localhost:6379"
+etcd:
+  endpoints:
+    - "localhost:2379"
+jwt:
+  jwtKey: "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"
+  refreshKey: "yyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyy"
+  credential:
+    activeKey: "k1"
+    keys:
+      k1: "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+oss:
+  accessKey: "ak"
+  secretKey: "sk"
+  bucketName: "bucket"
+  domainName: "cdn.example.com"
+log:
+  path: "./logs/app.log"
+prometheus:
+  namespace: "ccnubox"
+  routerCounter:
+    name: "http_requests_total"
+  activeConnections:
+    name: "active_connections"
+  durationTime:
+    name: "http_request_duration_seconds"
+`
+
+func newViper(t *testing.T, yaml string) *viper.Viper {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	BindEnv(v)
+	if err := v.ReadConfig(strings.NewReader(yaml)); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func withAllClients(yaml string) string {
+	return withClients(yaml, "discovery:///%s")
+}
+
+// withClients 为所有下游生成配置,format 中的 %s 会被替换成下游的名字
+func withClients(yaml, format string) string {
+	var sb strings.Builder
+	sb.WriteString(yaml)
+	sb.WriteString("grpc:\n  client:\n")
+	for _, name := range GrpcClients {
+		sb.WriteString("    " + name + ":\n      endpoint: \"" + fmt.Sprintf(format, name) + "\"\n")
+	}
+	return sb.String()
+}
+
+// writeEdKey 生成一个 ed25519 私钥文件,返回路径
+func writeEdKey(t *testing.T) string {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwt.pem")
+	if err = os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	edKey := writeEdKey(t)
+	tests := []struct {
+		name    string
+		yaml    string
+		env     map[string]string
+		wantErr []string // 错误信息中必须同时包含的 key
+		check   func(t *testing.T, cfg *Config)
+	}{
+		{
+			name: "Valid config with defaults",
+			yaml: withAllClients(validYaml),
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.HTTP.ShutdownTimeout <= 0 || cfg.Health.Timeout <= 0 {
+					t.Errorf("defaults not applied: %+v %+v", cfg.HTTP, cfg.Health)
+				}
+			},
+		},
+		{
+			name: "Env overrides config file",
+			yaml: withAllClients(validYaml),
+			env: map[string]string{
+				"BFF_JWT_JWTKEY":                "zzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzz",
+				"BFF_GRPC_CLIENT_CARD_ENDPOINT": "direct://127.0.0.1:9000",
+			},
+			check: func(t *testing.T, cfg *Config) {
+				if cfg.JWT.JwtKey != "zzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzz" {
+					t.Errorf("jwt.jwtKey = %q, want env value", cfg.JWT.JwtKey)
+				}
+				if got := cfg.Grpc.Lookup("card").Endpoint; got != "direct://127.0.0.1:9000" {
+					t.Errorf("grpc.client.card.endpoint = %q, want env value", got)
+				}
+			},
+		},
+		{
+			name: "Credential keys not in config file injected from env",
+			yaml: withAllClients(validYaml),
+			env: map[string]string{
+				"BFF_JWT_CREDENTIAL_ACTIVEKEY": "k2",
+				"BFF_JWT_CREDENTIAL_KEYS_K2":   "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA=",
+			},
+			check: func(t *testing.T, cfg *Config) {
+				if len(cfg.JWT.Credential.Keys) != 2 || cfg.JWT.Credential.Keys["k2"] == "" {
+					t.Errorf("jwt.credential.keys = %v, want k1 and k2", cfg.JWT.Credential.Keys)
+				}
+			},
+		},
+		{
+			name: "Etcd not required for direct endpoints",
+			yaml: withClients(strings.Replace(validYaml, "    - \"localhost:2379\"\n", "", 1), "direct://%s:9000"),
+			check: func(t *testing.T, cfg *Config) {
+				if got := cfg.Grpc.Lookup("card").Target(); got != "direct:///card:9000" {
+					t.Errorf("card target = %q, want direct:///card:9000", got)
+				}
+			},
+		},
+		{
+			name:    "Etcd required for discovery endpoints",
+			yaml:    withAllClients(strings.Replace(validYaml, "    - \"localhost:2379\"\n", "", 1)),
+			wantErr: []string{"etcd.endpoints"},
+		},
+		{
+			name: "Every problem reported at once",
+			yaml: strings.Replace(validYaml, `jwtKey: "xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx"`, `jwtKey: "short"`, 1),
+			wantErr: []string{
+				"grpc.client.card",
+				"grpc.client.user",
+				"jwt.jwtKey",
+			},
+		},
+		{
+			name: "Asymmetric signing without hmac keys",
+			yaml: withAllClients(strings.Replace(validYaml,
+				"  jwtKey: \"xxxxxxxxxxxxxxxxxxxxxxxxxxxxxxxx\"\n  refreshKey: \"yyyyyyyyyyyyyyyyyyyyyyyyyyyyyyyy\"\n",
+				"  algorithm: \"EdDSA\"\n  signingKeys:\n    activeKid: \"k1\"\n    keys:\n      - kid: \"k1\"\n        privateKeyFile: \""+edKey+"\"\n", 1)),
+			check: func(t *testing.T, cfg *Config) {
+				access, _, err := cfg.JWT.KeySets()
+				if err != nil || len(access.JWKS().Keys) != 1 {
+					t.Errorf("KeySets() = %v, %v, want one public key", access, err)
+				}
+			},
+		},
+		{
+			name:    "Signing key must have a private key for active kid",
+			yaml:    withAllClients(strings.Replace(validYaml, "  refreshKey:", "  algorithm: \"RS256\"\n  signingKeys:\n    activeKid: \"k1\"\n  refreshKey:", 1)),
+			wantErr: []string{"jwt.signingKeys"},
+		},
+		{
+			name:    "Credential active key must exist",
+			yaml:    withAllClients(strings.Replace(validYaml, `activeKey: "k1"`, `activeKey: "k2"`, 1)),
+			wantErr: []string{"jwt.credential"},
+		},
+		{
+			name: "RBAC roles must be well formed",
+			yaml: withAllClients(validYaml + "rbac:\n  roles:\n    editor: [\"banner:write\", \"Banner\"]\n  assignments:\n    \"2023214414\": [\"writer\"]\n"),
+			wantErr: []string{
+				"rbac.roles.editor",
+				"rbac.assignments.2023214414",
+			},
+		},
+		{
+			name: "RBAC permissions and role names must be known",
+			yaml: withAllClients(validYaml + "rbac:\n  roles:\n    editor: [\"banner:delete\"]\n    \"ops team\": [\"banner:write\"]\n"),
+			wantErr: []string{
+				"rbac.roles.editor: 未知的权限 \"banner:delete\"",
+				"rbac.roles.ops team: 非法的角色名",
+			},
+		},
+		{
+			name:    "Kafka audit sink needs brokers",
+			yaml:    withAllClients(validYaml + "audit:\n  sink: \"kafka\"\n"),
+			wantErr: []string{"kafka.addrs"},
+		},
+		{
+			name:    "Bad log path and runtime config",
+			yaml:    withAllClients(strings.Replace(validYaml, `path: "./logs/app.log"`, "path: \".\"\n  level: \"loud\"", 1)),
+			wantErr: []string{"log.path", "log.level"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, val := range tt.env {
+				t.Setenv(k, val)
+			}
+			cfg, err := Load(newViper(t, tt.yaml))
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("Load() error = %v", err)
+				}
+				if tt.check != nil {
+					tt.check(t, cfg)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Load() error = nil, want %v", tt.wantErr)
+			}
+			for _, key := range tt.wantErr {
+				if !strings.Contains(err.Error(), key) {
+					t.Errorf("Load() error = %v, want it to mention %s", err, key)
+				}
+			}
+		})
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg, err := Load(newViper(t, withAllClients(validYaml)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Redis.Password = "redis-secret"
+	out := fmt.Sprint(cfg.Redacted())
+	for _, secret := range []string{cfg.JWT.JwtKey, cfg.JWT.RefreshKey, cfg.JWT.Credential.Keys["k1"], cfg.Redis.Password} {
+		if strings.Contains(out, secret) {
+			t.Errorf("Redacted() leaks %q: %s", secret, out)
+		}
+	}
+	oss := cfg.Redacted()["oss"].(map[string]any)
+	if oss["accessKey"] != redactedValue || oss["secretKey"] != redactedValue || oss["domainName"] != "cdn.example.com" {
+		t.Errorf("Redacted() oss = %v", oss)
+	}
+}
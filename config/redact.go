@@ -0,0 +1,81 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// Redacted 把配置转换成以 yaml key 为键的 map,带 secret:"true" 标记的字段会被打码,用于对外展示
+func (c *Config) Redacted() map[string]any {
+	return redact(reflect.ValueOf(*c)).(map[string]any)
+}
+
+// Redacted 同 Config.Redacted,热更新的配置要单独取最新的一份
+func (c *RuntimeConfig) Redacted() map[string]any {
+	return redact(reflect.ValueOf(*c)).(map[string]any)
+}
+
+const redactedValue = "******"
+
+func redact(v reflect.Value) any {
+	if v.Type() == reflect.TypeOf(time.Duration(0)) {
+		return v.Interface().(time.Duration).String()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		res := make(map[string]any, v.NumField())
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := f.Tag.Get("yaml")
+			if name == "" || name == "-" {
+				continue
+			}
+			if f.Tag.Get("secret") == "true" {
+				// 没有配置的密钥保持为空,方便看出是不是漏配了
+				if !v.Field(i).IsZero() {
+					res[name] = redactedValue
+				} else {
+					res[name] = ""
+				}
+				continue
+			}
+			res[name] = redact(v.Field(i))
+		}
+		return res
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool { return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j]) })
+		// map[string]struct{} 当集合用,展示成列表
+		if v.Type().Elem() == reflect.TypeOf(struct{}{}) {
+			res := make([]any, len(keys))
+			for i, k := range keys {
+				res[i] = k.Interface()
+			}
+			return res
+		}
+		res := make(map[string]any, v.Len())
+		for _, k := range keys {
+			res[fmt.Sprint(k.Interface())] = redact(v.MapIndex(k))
+		}
+		return res
+	case reflect.Slice:
+		if v.IsNil() {
+			return []any{}
+		}
+		res := make([]any, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			res[i] = redact(v.Index(i))
+		}
+		return res
+	case reflect.Pointer:
+		if v.IsNil() {
+			return nil
+		}
+		return redact(v.Elem())
+	default:
+		return v.Interface()
+	}
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"github.com/asynccnu/bff/pkg/logger"
+	"github.com/fsnotify/fsnotify"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/viper"
+	"sync"
+)
+
+// Reloader 监听配置文件的变化,校验通过后整体替换 Runtime 中的配置
+type Reloader struct {
+	rt      *Runtime
+	l       logger.Logger
+	counter *prometheus.CounterVec // label: result
+	lock    sync.Mutex             // 保证同一时间只有一次热更新在执行
+}
+
+func NewReloader(rt *Runtime, l logger.Logger, counter *prometheus.CounterVec) *Reloader {
+	return &Reloader{rt: rt, l: l, counter: counter}
+}
+
+// Watch 开始监听配置文件,只需要调用一次
+func (r *Reloader) Watch() {
+	viper.OnConfigChange(func(in fsnotify.Event) {
+		r.Reload()
+	})
+	viper.WatchConfig()
+}
+
+// Reload 重新读取配置文件并尝试应用,任何一项校验失败都会保留旧的配置
+func (r *Reloader) Reload() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	file := viper.ConfigFileUsed()
+	// 用一个新的 viper 实例读取,避免读到一半的文件污染全局的 viper
+	v := viper.New()
+	v.SetConfigType("yaml")
+	v.SetConfigFile(file)
+	BindEnv(v)
+	if err := v.ReadInConfig(); err != nil {
+		r.counter.WithLabelValues("failure").Inc()
+		r.l.Error("配置热更新失败:读取配置文件出错,继续使用旧配置", logger.Error(err), logger.String("file", file))
+		return
+	}
+
+	cfg, err := LoadRuntimeConfig(v)
+	if err != nil {
+		r.counter.WithLabelValues("failure").Inc()
+		r.l.Error("配置热更新失败:配置校验不通过,继续使用旧配置", logger.Error(err), logger.String("file", file))
+		return
+	}
+
+	r.rt.Swap(cfg)
+	r.counter.WithLabelValues("success").Inc()
+	r.l.Info("配置热更新成功",
+		logger.String("file", file),
+		logger.Int("administrators", len(cfg.Administrators)),
+		logger.String("logLevel", cfg.LogLevel.String()),
+		logger.Int64("reloaded", r.rt.Reloaded()),
+	)
+}
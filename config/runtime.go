@@ -0,0 +1,232 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"path"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// RuntimeConfig 可以在运行时热更新的配置,每次更新都会整体替换,不会原地修改
+type RuntimeConfig struct {
+	Administrators map[string]struct{} `yaml:"administrators"`
+	Cors           CorsConfig          `yaml:"cors"`
+	RateLimit      RateLimitConfig     `yaml:"rateLimit"`
+	LoginGuard     LoginGuardConfig    `yaml:"loginGuard"`
+	SessionCheck   SessionCheckConfig  `yaml:"sessionCheck"`
+	LogLevel       zapcore.Level       `yaml:"logLevel"`
+}
+
+type CorsConfig struct {
+	AllowOrigins []string `yaml:"allowOrigins"` // 允许跨域的来源,支持 * 通配符,为空表示全部允许
+}
+
+type RateLimitConfig struct {
+	Enabled   bool          `yaml:"enabled"`
+	Interval  time.Duration `yaml:"interval"`  // 滑动窗口大小
+	Threshold int           `yaml:"threshold"` // 窗口内单个 IP 允许的最大请求数
+}
+
+// LoginGuardConfig 登录接口的防爆破配置
+type LoginGuardConfig struct {
+	PerStudent RateLimitConfig `yaml:"perStudent"` // 单个学号的登录尝试次数限制
+	PerIP      RateLimitConfig `yaml:"perIP"`      // 单个 IP 的登录尝试次数限制
+	Lockout    LockoutConfig   `yaml:"lockout"`
+}
+
+// LockoutConfig 连续密码错误 Threshold 次之后锁定学号 BaseDuration,之后每错一次锁定时间翻倍,最长 MaxDuration
+type LockoutConfig struct {
+	Threshold    int           `yaml:"threshold"`    // 0 表示不锁定
+	Window       time.Duration `yaml:"window"`       // 超过这个时间没有再输错,失败次数清零,默认 15 分钟
+	BaseDuration time.Duration `yaml:"baseDuration"` // 默认 1 分钟
+	MaxDuration  time.Duration `yaml:"maxDuration"`  // 默认 1 小时
+}
+
+// 会话检查出错(一般是 redis 不可用)时的处理方式
+const (
+	SessionCheckFailClosed = "closed" // 当作认证失败,和之前的行为一致
+	SessionCheckFailOpen   = "open"   // 直接放行,已经注销的会话在故障期间也能继续使用
+	SessionCheckFailCache  = "cache"  // 放行,但是本地缓存中最近确认过已注销的会话仍然拒绝
+)
+
+type SessionCheckConfig struct {
+	OnError  string        `yaml:"onError"`  // 见 SessionCheckFailClosed 等,默认 closed
+	CacheTTL time.Duration `yaml:"cacheTTL"` // 已注销的会话在本地缓存中保留的时间,默认 10 分钟
+}
+
+// IsAdmin 判断学号是否在管理员名单中
+func (c *RuntimeConfig) IsAdmin(studentId string) bool {
+	_, ok := c.Administrators[studentId]
+	return ok
+}
+
+// AllowOrigin 判断跨域来源是否被允许
+func (c *RuntimeConfig) AllowOrigin(origin string) bool {
+	if len(c.Cors.AllowOrigins) == 0 {
+		return true
+	}
+	for _, pattern := range c.Cors.AllowOrigins {
+		if pattern == "*" || pattern == origin {
+			return true
+		}
+		if ok, _ := path.Match(pattern, origin); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadRuntimeConfig 从 viper 中解析可热更新的配置并校验,有任何一项不合法都会返回错误
+// 所有的问题会被一次性返回,而不是遇到第一个就停下
+func LoadRuntimeConfig(v *viper.Viper) (*RuntimeConfig, error) {
+	var (
+		errs           []error
+		administrators []string
+		cors           CorsConfig
+		rateLimit      RateLimitConfig
+		loginGuard     LoginGuardConfig
+		sessionCheck   SessionCheckConfig
+		level          string
+	)
+
+	if err := v.UnmarshalKey("administrators", &administrators); err != nil {
+		errs = append(errs, fmt.Errorf("administrators: %w", err))
+	}
+	admins := make(map[string]struct{}, len(administrators))
+	for _, admin := range administrators {
+		if admin == "" || strings.TrimSpace(admin) != admin {
+			errs = append(errs, fmt.Errorf("administrators: 非法的学号 %q", admin))
+			continue
+		}
+		admins[admin] = struct{}{}
+	}
+
+	if err := v.UnmarshalKey("cors", &cors); err != nil {
+		errs = append(errs, fmt.Errorf("cors: %w", err))
+	}
+	for _, pattern := range cors.AllowOrigins {
+		if _, err := path.Match(pattern, ""); err != nil {
+			errs = append(errs, fmt.Errorf("cors.allowOrigins: 非法的匹配规则 %q", pattern))
+		}
+	}
+
+	if err := v.UnmarshalKey("rateLimit", &rateLimit); err != nil {
+		errs = append(errs, fmt.Errorf("rateLimit: %w", err))
+	}
+	if rateLimit.Enabled && (rateLimit.Interval <= 0 || rateLimit.Threshold <= 0) {
+		errs = append(errs, errors.New("rateLimit: 开启限流时 interval 和 threshold 必须大于 0"))
+	}
+
+	if err := v.UnmarshalKey("loginGuard", &loginGuard); err != nil {
+		errs = append(errs, fmt.Errorf("loginGuard: %w", err))
+	}
+	if limit := loginGuard.PerStudent; limit.Enabled && (limit.Interval <= 0 || limit.Threshold <= 0) {
+		errs = append(errs, errors.New("loginGuard.perStudent: 开启限流时 interval 和 threshold 必须大于 0"))
+	}
+	if limit := loginGuard.PerIP; limit.Enabled && (limit.Interval <= 0 || limit.Threshold <= 0) {
+		errs = append(errs, errors.New("loginGuard.perIP: 开启限流时 interval 和 threshold 必须大于 0"))
+	}
+	lockout := &loginGuard.Lockout
+	if lockout.Threshold < 0 || lockout.Window < 0 || lockout.BaseDuration < 0 || lockout.MaxDuration < 0 {
+		errs = append(errs, errors.New("loginGuard.lockout: 不能为负数"))
+	}
+	if lockout.Window == 0 {
+		lockout.Window = 15 * time.Minute
+	}
+	if lockout.BaseDuration == 0 {
+		lockout.BaseDuration = time.Minute
+	}
+	if lockout.MaxDuration == 0 {
+		lockout.MaxDuration = time.Hour
+	}
+	if lockout.MaxDuration < lockout.BaseDuration {
+		errs = append(errs, errors.New("loginGuard.lockout: maxDuration 不能小于 baseDuration"))
+	}
+
+	if err := v.UnmarshalKey("sessionCheck", &sessionCheck); err != nil {
+		errs = append(errs, fmt.Errorf("sessionCheck: %w", err))
+	}
+	switch sessionCheck.OnError {
+	case "":
+		sessionCheck.OnError = SessionCheckFailClosed
+	case SessionCheckFailClosed, SessionCheckFailOpen, SessionCheckFailCache:
+	default:
+		errs = append(errs, fmt.Errorf("sessionCheck.onError: 只支持 %s、%s 和 %s", SessionCheckFailClosed, SessionCheckFailOpen, SessionCheckFailCache))
+	}
+	if sessionCheck.CacheTTL < 0 {
+		errs = append(errs, errors.New("sessionCheck.cacheTTL: 不能为负数"))
+	}
+	if sessionCheck.CacheTTL == 0 {
+		sessionCheck.CacheTTL = 10 * time.Minute
+	}
+
+	// 没有配置的时候保持原来的 debug 级别
+	logLevel := zapcore.DebugLevel
+	if level = v.GetString("log.level"); level != "" {
+		if err := logLevel.UnmarshalText([]byte(level)); err != nil {
+			errs = append(errs, fmt.Errorf("log.level: %w", err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return &RuntimeConfig{
+		Administrators: admins,
+		Cors:           cors,
+		RateLimit:      rateLimit,
+		LoginGuard:     loginGuard,
+		SessionCheck:   sessionCheck,
+		LogLevel:       logLevel,
+	}, nil
+}
+
+// Runtime 持有当前生效的 RuntimeConfig,读取方每次都通过 Load 拿到一份完整的快照
+type Runtime struct {
+	current  atomic.Pointer[RuntimeConfig]
+	level    zap.AtomicLevel
+	reloaded atomic.Int64
+}
+
+func NewRuntime(cfg *RuntimeConfig) *Runtime {
+	rt := &Runtime{level: zap.NewAtomicLevelAt(cfg.LogLevel)}
+	rt.current.Store(cfg)
+	return rt
+}
+
+// Load 返回当前生效的配置,调用方不要修改返回值
+func (r *Runtime) Load() *RuntimeConfig {
+	return r.current.Load()
+}
+
+// Swap 整体替换配置,日志级别会同步更新
+func (r *Runtime) Swap(cfg *RuntimeConfig) {
+	r.current.Store(cfg)
+	r.level.SetLevel(cfg.LogLevel)
+	r.reloaded.Add(1)
+}
+
+// Reloaded 返回启动以来成功热更新的次数
+func (r *Runtime) Reloaded() int64 {
+	return r.reloaded.Load()
+}
+
+// LogLevel 供 zap 使用的可动态调整的日志级别
+func (r *Runtime) LogLevel() zap.AtomicLevel {
+	return r.level
+}
+
+// IsAdmin 使 Runtime 可以直接作为管理员名单注入到各个 handler
+func (r *Runtime) IsAdmin(studentId string) bool {
+	return r.Load().IsAdmin(studentId)
+}
+
+// AllowOrigin 使 Runtime 可以直接作为跨域策略注入到 cors 中间件
+func (r *Runtime) AllowOrigin(origin string) bool {
+	return r.Load().AllowOrigin(origin)
+}
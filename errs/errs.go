@@ -1,8 +1,11 @@
 package errs
 
 import (
+	"fmt"
 	"github.com/asynccnu/bff/pkg/errorx"
+	"math"
 	"net/http"
+	"time"
 )
 
 // TODO 细化错误码,根据错误类型区分不同的错误码
@@ -14,6 +17,10 @@ const (
 	BAD_ENTITY_ERROR_CODE
 	ROLE_ERROR_CODE
 	INVALID_PARAM_VALUE_ERROR_CODE
+	TOO_MANY_REQUESTS_ERROR_CODE
+	LOGIN_LOCKED_ERROR_CODE
+	CSRF_ERROR_CODE
+	IMPERSONATION_READ_ONLY_ERROR_CODE
 )
 
 // 500
@@ -266,6 +273,93 @@ var (
 	USER_SID_Or_PASSPORD_ERROR = func(err error) error {
 		return errorx.New(http.StatusBadRequest, USER_SID_Or_PASSPORD_ERROR_CODE, "账号或者密码错误!", "user", err)
 	}
+
+	GET_SESSIONS_ERROR = func(err error) error {
+		return errorx.New(http.StatusInternalServerError, INTERNAL_SERVER_ERROR_CODE, "获取登录设备失败!", "user", err)
+	}
+
+	REVOKE_SESSION_ERROR = func(err error) error {
+		return errorx.New(http.StatusInternalServerError, INTERNAL_SERVER_ERROR_CODE, "注销登录设备失败!", "user", err)
+	}
+
+	SESSION_NOT_FOUND_ERROR = func(err error) error {
+		return errorx.New(http.StatusNotFound, INVALID_PARAM_VALUE_ERROR_CODE, "登录设备不存在!", "user", err)
+	}
+
+	GET_CURRENT_USER_ERROR = func(err error) error {
+		return errorx.New(http.StatusInternalServerError, INTERNAL_SERVER_ERROR_CODE, "获取当前用户信息失败!", "user", err)
+	}
+
+	GET_PROFILE_ERROR = func(err error) error {
+		return errorx.New(http.StatusInternalServerError, INTERNAL_SERVER_ERROR_CODE, "获取个人资料失败!", "user", err)
+	}
+
+	EDIT_PROFILE_ERROR = func(err error) error {
+		return errorx.New(http.StatusInternalServerError, INTERNAL_SERVER_ERROR_CODE, "修改个人资料失败!", "user", err)
+	}
+
+	PROFILE_NOT_FOUND_ERROR = func(err error) error {
+		return errorx.New(http.StatusNotFound, INVALID_PARAM_VALUE_ERROR_CODE, "用户不存在!", "user", err)
+	}
+
+	IMPERSONATE_ERROR = func(err error) error {
+		return errorx.New(http.StatusInternalServerError, INTERNAL_SERVER_ERROR_CODE, "模拟登录失败!", "user", err)
+	}
+
+	// LOGIN_LOCKED_ERROR 和普通的限流分开,客户端可以据此提示用户多久之后再试
+	LOGIN_LOCKED_ERROR = func(retryAfter time.Duration, err error) error {
+		msg := fmt.Sprintf("登录尝试过于频繁,请 %d 秒后再试!", int64(math.Ceil(retryAfter.Seconds())))
+		return errorx.New(http.StatusTooManyRequests, LOGIN_LOCKED_ERROR_CODE, msg, "user", err)
+	}
+
+	UNLOCK_LOGIN_ERROR = func(err error) error {
+		return errorx.New(http.StatusInternalServerError, INTERNAL_SERVER_ERROR_CODE, "解除登录锁定失败!", "user", err)
+	}
+)
+
+// RBAC
+var (
+	GET_ROLES_ERROR = func(err error) error {
+		return errorx.New(http.StatusInternalServerError, INTERNAL_SERVER_ERROR_CODE, "获取角色失败!", "rbac", err)
+	}
+
+	SAVE_ROLE_ERROR = func(err error) error {
+		return errorx.New(http.StatusInternalServerError, INTERNAL_SERVER_ERROR_CODE, "保存角色失败!", "rbac", err)
+	}
+
+	ROLE_NOT_FOUND_ERROR = func(err error) error {
+		return errorx.New(http.StatusNotFound, INVALID_PARAM_VALUE_ERROR_CODE, "角色不存在!", "rbac", err)
+	}
+)
+
+// API Key
+var (
+	GET_API_KEYS_ERROR = func(err error) error {
+		return errorx.New(http.StatusInternalServerError, INTERNAL_SERVER_ERROR_CODE, "获取 API Key 失败!", "apikey", err)
+	}
+
+	CREATE_API_KEY_ERROR = func(err error) error {
+		return errorx.New(http.StatusInternalServerError, INTERNAL_SERVER_ERROR_CODE, "创建 API Key 失败!", "apikey", err)
+	}
+
+	DELETE_API_KEY_ERROR = func(err error) error {
+		return errorx.New(http.StatusInternalServerError, INTERNAL_SERVER_ERROR_CODE, "吊销 API Key 失败!", "apikey", err)
+	}
+
+	API_KEY_NOT_FOUND_ERROR = func(err error) error {
+		return errorx.New(http.StatusNotFound, INVALID_PARAM_VALUE_ERROR_CODE, "API Key 不存在!", "apikey", err)
+	}
+)
+
+// Audit
+var (
+	QUERY_AUDIT_LOGS_ERROR = func(err error) error {
+		return errorx.New(http.StatusInternalServerError, INTERNAL_SERVER_ERROR_CODE, "查询审计日志失败!", "audit", err)
+	}
+
+	AUDIT_QUERY_UNSUPPORTED_ERROR = func(err error) error {
+		return errorx.New(http.StatusNotImplemented, INTERNAL_SERVER_ERROR_CODE, "当前的审计日志不支持查询,请到对应的存储中查看!", "audit", err)
+	}
 )
 
 // Common
@@ -278,6 +372,10 @@ var (
 		return errorx.New(http.StatusForbidden, ROLE_ERROR_CODE, "访问权限不足", "Common", err)
 	}
 
+	CHECK_PERMISSION_ERROR = func(err error) error {
+		return errorx.New(http.StatusInternalServerError, INTERNAL_SERVER_ERROR_CODE, "权限校验失败", "Common", err)
+	}
+
 	TYPE_CHANGE_ERROR = func(err error) error {
 		return errorx.New(http.StatusInternalServerError, TYPE_CHANGE_ERROR_CODE, "类型转换错误", "Common", err)
 	}
@@ -285,6 +383,10 @@ var (
 	INVALID_PARAM_VALUE_ERROR = func(err error) error {
 		return errorx.New(http.StatusBadRequest, INVALID_PARAM_VALUE_ERROR_CODE, "非法的参数值", "Common", err)
 	}
+
+	TOO_MANY_REQUESTS_ERROR = func(err error) error {
+		return errorx.New(http.StatusTooManyRequests, TOO_MANY_REQUESTS_ERROR_CODE, "请求过于频繁,请稍后再试", "Common", err)
+	}
 )
 
 // website
@@ -308,6 +410,14 @@ var (
 		return errorx.New(http.StatusUnauthorized, UNAUTHORIED_ERROR_CODE, "Authorization错误", "authorization", err)
 	}
 
+	CSRF_ERROR = func(err error) error {
+		return errorx.New(http.StatusForbidden, CSRF_ERROR_CODE, "CSRF 校验失败,请带上 X-CSRF-Token 请求头", "authorization", err)
+	}
+
+	IMPERSONATION_READ_ONLY_ERROR = func(err error) error {
+		return errorx.New(http.StatusForbidden, IMPERSONATION_READ_ONLY_ERROR_CODE, "模拟登录只能访问查询接口", "authorization", err)
+	}
+
 	AUTH_PASSED_ERROR = func(err error) error {
 		return errorx.New(http.StatusUnauthorized, UNAUTHORIED_ERROR_CODE, "Authorization过期", "authorization", err)
 	}
@@ -0,0 +1,15 @@
+package ioc
+
+import (
+	"github.com/asynccnu/bff/web/apikey"
+	"github.com/asynccnu/bff/web/middleware"
+)
+
+// InitApiKeyAuthenticator 登录中间件只依赖接口,免得 middleware 反过来依赖 redis 的实现
+func InitApiKeyAuthenticator(svc *apikey.Service) middleware.ApiKeyAuthenticator {
+	return svc
+}
+
+func InitApiKeyHandler(svc *apikey.Service, auth *middleware.PermissionMiddleware) *apikey.ApiKeyHandler {
+	return apikey.NewApiKeyHandler(svc, auth)
+}
@@ -0,0 +1,55 @@
+package ioc
+
+import (
+	"github.com/IBM/sarama"
+	"github.com/asynccnu/bff/config"
+	"github.com/asynccnu/bff/pkg/auditx"
+	"github.com/asynccnu/bff/pkg/logger"
+	"github.com/asynccnu/bff/web/audit"
+	"github.com/asynccnu/bff/web/middleware"
+	"github.com/redis/go-redis/v9"
+)
+
+// auditStreamKey 审计日志在 redis 中的 stream
+const auditStreamKey = "ccnubox:audit"
+
+func InitAuditSink(conf *config.Config, cmd redis.Cmdable) (auditx.Sink, func()) {
+	cfg := conf.Audit
+	switch cfg.Sink {
+	case config.AuditSinkKafka:
+		saramaCfg := sarama.NewConfig()
+		// SyncProducer 要求开启,审计日志也需要确认写入成功
+		saramaCfg.Producer.Return.Successes = true
+		saramaCfg.Producer.RequiredAcks = sarama.WaitForAll
+		producer, err := sarama.NewSyncProducer(conf.Kafka.Addrs, saramaCfg)
+		if err != nil {
+			panic(err)
+		}
+		return auditx.NewKafkaSink(producer, cfg.Topic), func() {
+			_ = producer.Close()
+		}
+	case config.AuditSinkFile:
+		sink, err := auditx.NewFileSink(cfg.Path)
+		if err != nil {
+			panic(err)
+		}
+		return sink, func() {
+			_ = sink.Close()
+		}
+	default:
+		return auditx.NewRedisStreamSink(cmd, auditStreamKey, cfg.MaxLen), func() {}
+	}
+}
+
+func InitAuditRecorder(sink auditx.Sink, l logger.Logger) *audit.Recorder {
+	return audit.NewRecorder(sink, l)
+}
+
+// InitAuditor 登录中间件用它记录模拟登录期间的请求
+func InitAuditor(recorder *audit.Recorder) middleware.Auditor {
+	return recorder
+}
+
+func InitAuditHandler(sink auditx.Sink, auth *middleware.PermissionMiddleware) *audit.AuditHandler {
+	return audit.NewAuditHandler(sink, auth)
+}
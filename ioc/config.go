@@ -0,0 +1,16 @@
+package ioc
+
+import (
+	"github.com/asynccnu/bff/config"
+	"github.com/asynccnu/bff/pkg/logger"
+	"github.com/asynccnu/bff/pkg/prometheusx"
+)
+
+// InitRuntimeConfig 启动时的值已经在 config.Load 中校验过了,热更新时不合法则保留旧的配置
+func InitRuntimeConfig(conf *config.Config) *config.Runtime {
+	return config.NewRuntime(conf.Runtime)
+}
+
+func InitConfigReloader(rt *config.Runtime, l logger.Logger, p *prometheusx.PrometheusCounter) *config.Reloader {
+	return config.NewReloader(rt, l, p.ConfigReloadCounter)
+}
@@ -1,20 +1,43 @@
 package ioc
 
 import (
-	"github.com/spf13/viper"
+	"github.com/asynccnu/bff/config"
+	"github.com/asynccnu/bff/pkg/healthx"
 	clientv3 "go.etcd.io/etcd/client/v3"
+	"sync"
 )
 
-func InitEtcdClient() *clientv3.Client {
-	//初始化etcd
-	var cfg clientv3.Config
-	err := viper.UnmarshalKey("etcd", &cfg)
-	if err != nil {
-		panic(err)
-	}
-	client, err := clientv3.New(cfg)
-	if err != nil {
-		panic(err)
+// EtcdClient 延迟创建的 etcd 客户端,所有下游都用 direct:// 或者静态地址时就不会去连接 etcd
+type EtcdClient struct {
+	conf config.EtcdConfig
+	reg  *healthx.Registry
+
+	once   sync.Once
+	client *clientv3.Client
+	err    error
+}
+
+func InitEtcdClient(conf *config.Config, reg *healthx.Registry) (*EtcdClient, func()) {
+	e := &EtcdClient{conf: conf.Etcd, reg: reg}
+	return e, func() {
+		if e.client != nil {
+			_ = e.client.Close()
+		}
 	}
-	return client
+}
+
+// Get 第一次调用时才真正创建客户端,并把 etcd 加入就绪探针
+func (e *EtcdClient) Get() (*clientv3.Client, error) {
+	e.once.Do(func() {
+		e.client, e.err = clientv3.New(clientv3.Config{
+			Endpoints:   e.conf.Endpoints,
+			Username:    e.conf.Username,
+			Password:    e.conf.Password,
+			DialTimeout: e.conf.DialTimeout,
+		})
+		if e.err == nil {
+			e.reg.Register("etcd", healthx.NewEtcdChecker(e.client))
+		}
+	})
+	return e.client, e.err
 }
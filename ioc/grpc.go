@@ -0,0 +1,98 @@
+package ioc
+
+import (
+	"context"
+	"fmt"
+	"github.com/asynccnu/bff/config"
+	"github.com/asynccnu/bff/pkg/grpcx"
+	"github.com/asynccnu/bff/pkg/healthx"
+	"github.com/asynccnu/bff/pkg/logger"
+	"github.com/asynccnu/bff/web/admin"
+	"github.com/go-kratos/kratos/contrib/registry/etcd/v2"
+	"github.com/go-kratos/kratos/v2/registry"
+	kgrpc "github.com/go-kratos/kratos/v2/transport/grpc"
+	"google.golang.org/grpc"
+	"sync"
+)
+
+// GrpcClientFactory 按照 grpc.client.<name> 的配置创建客户端,注册中心、就绪探针和连接的关闭都在这里统一处理
+type GrpcClientFactory struct {
+	conf config.GrpcConfig
+	ecli *EtcdClient
+	reg  *healthx.Registry
+	l    logger.Logger
+
+	once      sync.Once
+	discovery registry.Discovery
+
+	mu      sync.Mutex
+	clients []string // 已经创建的客户端,给管理接口展示用
+}
+
+func InitGrpcClientFactory(conf *config.Config, ecli *EtcdClient, reg *healthx.Registry, l logger.Logger) *GrpcClientFactory {
+	return &GrpcClientFactory{
+		conf: conf.Grpc,
+		ecli: ecli,
+		reg:  reg,
+		l:    l,
+	}
+}
+
+// etcdDiscovery 只有 discovery:/// 形式的下游才会用到,第一次用到的时候才连接 etcd
+func (f *GrpcClientFactory) etcdDiscovery() registry.Discovery {
+	f.once.Do(func() {
+		client, err := f.ecli.Get()
+		if err != nil {
+			panic(fmt.Errorf("连接 etcd 失败: %w", err))
+		}
+		f.discovery = etcd.New(client)
+	})
+	return f.discovery
+}
+
+// newGrpcClient 建立到下游 name 的连接并用 newClient 包装成具体的客户端
+// 新增一个下游只需要在配置文件里加一项 grpc.client.<name>,再在 grpc_client.go 里加一行调用
+func newGrpcClient[T any](f *GrpcClientFactory, name string, newClient func(grpc.ClientConnInterface) T) (T, func()) {
+	cfg := f.conf.Lookup(name)
+	var discovery registry.Discovery
+	if cfg.NeedsDiscovery() {
+		discovery = f.etcdDiscovery()
+	}
+	cc, err := grpcx.Dial(context.Background(), cfg, discovery,
+		kgrpc.WithUnaryInterceptor(grpcx.TimeoutLogInterceptor(f.l, name)),
+	)
+	if err != nil {
+		panic(fmt.Errorf("初始化 grpc 客户端 %s 失败: %w", name, err))
+	}
+	f.reg.Register(name, healthx.NewGrpcChecker(cc))
+	f.mu.Lock()
+	f.clients = append(f.clients, name)
+	f.mu.Unlock()
+	return newClient(cc), func() {
+		_ = cc.Close()
+	}
+}
+
+// Resolve 查询每个已创建的客户端当前解析到的地址
+func (f *GrpcClientFactory) Resolve(ctx context.Context) map[string]admin.GrpcClientInfo {
+	f.mu.Lock()
+	names := append([]string(nil), f.clients...)
+	f.mu.Unlock()
+
+	res := make(map[string]admin.GrpcClientInfo, len(names))
+	for _, name := range names {
+		cfg := f.conf.Lookup(name)
+		info := admin.GrpcClientInfo{Target: cfg.Target()}
+		var discovery registry.Discovery
+		if cfg.NeedsDiscovery() {
+			discovery = f.etcdDiscovery()
+		}
+		addrs, err := grpcx.Resolve(ctx, cfg, discovery)
+		if err != nil {
+			info.Error = err.Error()
+		}
+		info.Addresses = addrs
+		res[name] = info
+	}
+	return res
+}
@@ -0,0 +1,89 @@
+package ioc
+
+import (
+	bannerv1 "github.com/asynccnu/be-api/gen/proto/banner/v1"
+	calendarv1 "github.com/asynccnu/be-api/gen/proto/calendar/v1"
+	cardv1 "github.com/asynccnu/be-api/gen/proto/card/v1"
+	ccnuv1 "github.com/asynccnu/be-api/gen/proto/ccnu/v1"
+	cs "github.com/asynccnu/be-api/gen/proto/classService/v1"
+	classlistv1 "github.com/asynccnu/be-api/gen/proto/classlist/v1"
+	counterv1 "github.com/asynccnu/be-api/gen/proto/counter/v1"
+	departmentv1 "github.com/asynccnu/be-api/gen/proto/department/v1"
+	elecpricev1 "github.com/asynccnu/be-api/gen/proto/elecprice/v1"
+	feedv1 "github.com/asynccnu/be-api/gen/proto/feed/v1"
+	feedbackv1 "github.com/asynccnu/be-api/gen/proto/feedback_help/v1"
+	gradev1 "github.com/asynccnu/be-api/gen/proto/grade/v1"
+	infoSumv1 "github.com/asynccnu/be-api/gen/proto/infoSum/v1"
+	staticv1 "github.com/asynccnu/be-api/gen/proto/static/v1"
+	userv1 "github.com/asynccnu/be-api/gen/proto/user/v1"
+	websitev1 "github.com/asynccnu/be-api/gen/proto/website/v1"
+	webclient "github.com/asynccnu/bff/web/client"
+)
+
+// 超时、重试、keepalive 等参数都在 grpc.client.<name> 中配置,这里的名字要和配置文件保持一致
+
+func InitBannerClient(f *GrpcClientFactory) (bannerv1.BannerServiceClient, func()) {
+	return newGrpcClient(f, "banner", bannerv1.NewBannerServiceClient)
+}
+
+func InitCalendarClient(f *GrpcClientFactory) (calendarv1.CalendarServiceClient, func()) {
+	return newGrpcClient(f, "calendar", calendarv1.NewCalendarServiceClient)
+}
+
+func InitCardClient(f *GrpcClientFactory) (cardv1.CardClient, func()) {
+	return newGrpcClient(f, "card", cardv1.NewCardClient)
+}
+
+// InitCCNUClient 华师的接口不太稳定,在 grpc 的重试之外再包一层业务上的重试
+func InitCCNUClient(f *GrpcClientFactory) (ccnuv1.CCNUServiceClient, func()) {
+	client, cleanup := newGrpcClient(f, "ccnu", ccnuv1.NewCCNUServiceClient)
+	return webclient.NewRetryCCNUClient(client, f.conf.Lookup("ccnu").RetryCnt), cleanup
+}
+
+func InitClassService(f *GrpcClientFactory) (cs.ClassServiceClient, func()) {
+	return newGrpcClient(f, "classService", cs.NewClassServiceClient)
+}
+
+func InitClassList(f *GrpcClientFactory) (classlistv1.ClasserClient, func()) {
+	return newGrpcClient(f, "classlist", classlistv1.NewClasserClient)
+}
+
+func InitCounterClient(f *GrpcClientFactory) (counterv1.CounterServiceClient, func()) {
+	return newGrpcClient(f, "counter", counterv1.NewCounterServiceClient)
+}
+
+func InitDepartmentClient(f *GrpcClientFactory) (departmentv1.DepartmentServiceClient, func()) {
+	return newGrpcClient(f, "department", departmentv1.NewDepartmentServiceClient)
+}
+
+func InitElecpriceClient(f *GrpcClientFactory) (elecpricev1.ElecpriceServiceClient, func()) {
+	return newGrpcClient(f, "elecprice", elecpricev1.NewElecpriceServiceClient)
+}
+
+func InitFeedClient(f *GrpcClientFactory) (feedv1.FeedServiceClient, func()) {
+	return newGrpcClient(f, "feed", feedv1.NewFeedServiceClient)
+}
+
+func InitFeedbackHelpClient(f *GrpcClientFactory) (feedbackv1.FeedbackHelpClient, func()) {
+	return newGrpcClient(f, "feedbackHelp", feedbackv1.NewFeedbackHelpClient)
+}
+
+func InitGradeClient(f *GrpcClientFactory) (gradev1.GradeServiceClient, func()) {
+	return newGrpcClient(f, "grade", gradev1.NewGradeServiceClient)
+}
+
+func InitInfoSumClient(f *GrpcClientFactory) (infoSumv1.InfoSumServiceClient, func()) {
+	return newGrpcClient(f, "infoSum", infoSumv1.NewInfoSumServiceClient)
+}
+
+func InitStaticClient(f *GrpcClientFactory) (staticv1.StaticServiceClient, func()) {
+	return newGrpcClient(f, "static", staticv1.NewStaticServiceClient)
+}
+
+func InitUserClient(f *GrpcClientFactory) (userv1.UserServiceClient, func()) {
+	return newGrpcClient(f, "user", userv1.NewUserServiceClient)
+}
+
+func InitWebsiteClient(f *GrpcClientFactory) (websitev1.WebsiteServiceClient, func()) {
+	return newGrpcClient(f, "website", websitev1.NewWebsiteServiceClient)
+}
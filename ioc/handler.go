@@ -17,8 +17,11 @@ import (
 	staticv1 "github.com/asynccnu/be-api/gen/proto/static/v1"
 	userv1 "github.com/asynccnu/be-api/gen/proto/user/v1"
 	websitev1 "github.com/asynccnu/be-api/gen/proto/website/v1"
+	"github.com/asynccnu/bff/config"
+	"github.com/asynccnu/bff/pkg/healthx"
 	"github.com/asynccnu/bff/pkg/htmlx"
 	"github.com/asynccnu/bff/pkg/logger"
+	"github.com/asynccnu/bff/web/admin"
 	"github.com/asynccnu/bff/web/banner"
 	"github.com/asynccnu/bff/web/calendar"
 	"github.com/asynccnu/bff/web/card"
@@ -28,187 +31,115 @@ import (
 	"github.com/asynccnu/bff/web/feed"
 	"github.com/asynccnu/bff/web/feedback_help"
 	"github.com/asynccnu/bff/web/grade"
+	"github.com/asynccnu/bff/web/health"
 	"github.com/asynccnu/bff/web/ijwt"
 	"github.com/asynccnu/bff/web/infoSum"
+	"github.com/asynccnu/bff/web/loginguard"
 	"github.com/asynccnu/bff/web/metrics"
+	"github.com/asynccnu/bff/web/middleware"
+	"github.com/asynccnu/bff/web/rbac"
 	"github.com/asynccnu/bff/web/static"
 	"github.com/asynccnu/bff/web/tube"
 	"github.com/asynccnu/bff/web/user"
 	"github.com/asynccnu/bff/web/website"
-	"github.com/ecodeclub/ekit/slice"
+	"github.com/asynccnu/bff/web/wellknown"
 	"github.com/qiniu/api.v7/v7/auth/qbox"
 	"github.com/qiniu/api.v7/v7/storage"
-	"github.com/spf13/viper"
 )
 
 func InitStaticHandler(
-	staticClient staticv1.StaticServiceClient) *static.StaticHandler {
-	var administrators []string
-	err := viper.UnmarshalKey("administrators", &administrators)
-	if err != nil {
-		panic(err)
-	}
+	staticClient staticv1.StaticServiceClient, auth *middleware.PermissionMiddleware) *static.StaticHandler {
 	return static.NewStaticHandler(staticClient,
 		map[string]htmlx.FileToHTMLConverter{},
-		slice.ToMapV(administrators, func(element string) (string, struct{}) {
-			return element, struct{}{}
-		}))
+		auth)
 }
 
 // InitCalendarHandler 初始化 CalendarHandler
 func InitCalendarHandler(
-	calendarClient calendarv1.CalendarServiceClient) *calendar.CalendarHandler {
-	var administrators []string
-	err := viper.UnmarshalKey("administrators", &administrators)
-	if err != nil {
-		panic(err)
-	}
+	calendarClient calendarv1.CalendarServiceClient, auth *middleware.PermissionMiddleware) *calendar.CalendarHandler {
 	return calendar.NewCalendarHandler(calendarClient,
-		slice.ToMapV(administrators, func(element string) (string, struct{}) {
-			return element, struct{}{}
-		}))
+		auth)
 }
 
 // InitBannerHandler 初始化 BannerHandler
 func InitBannerHandler(
-	bannerClient bannerv1.BannerServiceClient) *banner.BannerHandler {
-	var administrators []string
-	err := viper.UnmarshalKey("administrators", &administrators)
-	if err != nil {
-		panic(err)
-	}
+	bannerClient bannerv1.BannerServiceClient, auth *middleware.PermissionMiddleware) *banner.BannerHandler {
 	return banner.NewBannerHandler(bannerClient,
-		slice.ToMapV(administrators, func(element string) (string, struct{}) {
-			return element, struct{}{}
-		}))
+		auth)
 }
 
 // InitWebsiteHandler 初始化 WebsiteHandler
 func InitWebsiteHandler(
-	websiteClient websitev1.WebsiteServiceClient) *website.WebsiteHandler {
-	var administrators []string
-	err := viper.UnmarshalKey("administrators", &administrators)
-	if err != nil {
-		panic(err)
-	}
+	websiteClient websitev1.WebsiteServiceClient, auth *middleware.PermissionMiddleware) *website.WebsiteHandler {
 	return website.NewWebsiteHandler(websiteClient,
-		slice.ToMapV(administrators, func(element string) (string, struct{}) {
-			return element, struct{}{}
-		}))
+		auth)
 }
 
 // InitInfoSumHandler 初始化 InfoSumHandler
 func InitInfoSumHandler(
-	infoSumClient infoSumv1.InfoSumServiceClient) *infoSum.InfoSumHandler {
-	var administrators []string
-	err := viper.UnmarshalKey("administrators", &administrators)
-	if err != nil {
-		panic(err)
-	}
+	infoSumClient infoSumv1.InfoSumServiceClient, auth *middleware.PermissionMiddleware) *infoSum.InfoSumHandler {
 	return infoSum.NewInfoSumHandler(infoSumClient,
-		slice.ToMapV(administrators, func(element string) (string, struct{}) {
-			return element, struct{}{}
-		}))
+		auth)
 }
 
 // InitDepartmentHandler 初始化 DepartmentHandler
 func InitDepartmentHandler(
-	departmentClient departmentv1.DepartmentServiceClient) *department.DepartmentHandler {
-	var administrators []string
-	err := viper.UnmarshalKey("administrators", &administrators)
-	if err != nil {
-		panic(err)
-	}
+	departmentClient departmentv1.DepartmentServiceClient, auth *middleware.PermissionMiddleware) *department.DepartmentHandler {
 	return department.NewDepartmentHandler(departmentClient,
-		slice.ToMapV(administrators, func(element string) (string, struct{}) {
-			return element, struct{}{}
-		}))
+		auth)
 }
 
 func InitFeedHandler(
-	feedServiceClient feedv1.FeedServiceClient) *feed.FeedHandler {
-	var administrators []string
-	err := viper.UnmarshalKey("administrators", &administrators)
-	if err != nil {
-		panic(err)
-	}
+	feedServiceClient feedv1.FeedServiceClient, auth *middleware.PermissionMiddleware) *feed.FeedHandler {
 	return feed.NewFeedHandler(feedServiceClient,
-		slice.ToMapV(administrators, func(element string) (string, struct{}) {
-			return element, struct{}{}
-		}))
+		auth)
 }
 
 func InitElecpriceHandler(client elecpricev1.ElecpriceServiceClient) *elecprice.ElecPriceHandler {
-	var administrators []string
-	err := viper.UnmarshalKey("administrators", &administrators)
-	if err != nil {
-		panic(err)
-	}
-
-	return elecprice.NewElecPriceHandler(client,
-		slice.ToMapV(administrators, func(element string) (string, struct{}) {
-			return element, struct{}{}
-		}))
+	return elecprice.NewElecPriceHandler(client)
 }
 func InitClassHandler(client1 classlistv1.ClasserClient, client2 cs.ClassServiceClient) *class.ClassHandler {
-	var administrators []string
-	err := viper.UnmarshalKey("administrators", &administrators)
-	if err != nil {
-		panic(err)
-	}
-	return class.NewClassListHandler(client1, client2,
-		slice.ToMapV(administrators, func(element string) (string, struct{}) {
-			return element, struct{}{}
-		}))
+	return class.NewClassListHandler(client1, client2)
 }
 
 func InitGradeHandler(l logger.Logger, gradeClient gradev1.GradeServiceClient, counterServiceClient counterv1.CounterServiceClient) *grade.GradeHandler {
-	var administrators []string
-	err := viper.UnmarshalKey("administrators", &administrators)
-	if err != nil {
-		panic(err)
-	}
 	return grade.NewGradeHandler(
 		gradeClient,
 		counterServiceClient,
 		l,
-		slice.ToMapV(administrators, func(element string) (string, struct{}) { return element, struct{}{} }),
 	)
 }
 
-func InitFeedbackHelpHandler(client feedbackv1.FeedbackHelpClient) *feedback_help.FeedbackHelpHandler {
-	var administrators []string
-	err := viper.UnmarshalKey("administrators", &administrators)
-	if err != nil {
-		panic(err)
-	}
+func InitFeedbackHelpHandler(client feedbackv1.FeedbackHelpClient, auth *middleware.PermissionMiddleware) *feedback_help.FeedbackHelpHandler {
 	return feedback_help.NewFeedbackHelpHandler(client,
-		slice.ToMapV(administrators, func(element string) (string, struct{}) { return element, struct{}{} }))
+		auth)
 }
 
 func InitCardHandler(client cardv1.CardClient) *card.CardHandler {
-	var administrators []string
-	err := viper.UnmarshalKey("administrators", &administrators)
-	if err != nil {
-		panic(err)
-	}
-	return card.NewCardHandler(client,
-		slice.ToMapV(administrators, func(element string) (string, struct{}) { return element, struct{}{} }))
+	return card.NewCardHandler(client)
 }
 
-func InitUserHandler(hdl ijwt.Handler, userClient userv1.UserServiceClient, ccnuClient ccnuv1.CCNUServiceClient) *user.UserHandler {
-	var administrators []string
-	err := viper.UnmarshalKey("administrators", &administrators)
-	if err != nil {
-		panic(err)
-	}
-	return user.NewUserHandler(hdl, userClient, ccnuClient)
+func InitUserHandler(conf *config.Config, hdl ijwt.Handler, guard *loginguard.LoginGuard, r *rbac.RBAC,
+	userClient userv1.UserServiceClient, ccnuClient ccnuv1.CCNUServiceClient, auth *middleware.PermissionMiddleware) *user.UserHandler {
+	return user.NewUserHandler(hdl, guard, r, conf.OSS.DomainName, auth, userClient, ccnuClient)
 }
 
-func InitTubeHandler(putPolicy storage.PutPolicy, mac *qbox.Mac) *tube.TubeHandler {
-	return tube.NewTubeHandler(putPolicy, mac, viper.GetString("oss.domainName"))
+func InitTubeHandler(conf *config.Config, putPolicy storage.PutPolicy, mac *qbox.Mac) *tube.TubeHandler {
+	return tube.NewTubeHandler(putPolicy, mac, conf.OSS.DomainName)
 }
 
 func InitMetricsHandel() *metrics.MetricsHandler {
 	return metrics.NewMetricsHandler()
 }
+
+func InitHealthHandler(reg *healthx.Registry) *health.HealthHandler {
+	return health.NewHealthHandler(reg)
+}
+
+func InitAdminHandler(conf *config.Config, rt *config.Runtime, factory *GrpcClientFactory, guard *loginguard.LoginGuard, auth *middleware.PermissionMiddleware) *admin.AdminHandler {
+	return admin.NewAdminHandler(conf, rt, factory, guard, auth)
+}
+
+func InitWellKnownHandler(hdl ijwt.Handler) *wellknown.WellKnownHandler {
+	return wellknown.NewWellKnownHandler(hdl)
+}
@@ -0,0 +1,26 @@
+package ioc
+
+import (
+	"fmt"
+	"github.com/asynccnu/bff/config"
+	"github.com/asynccnu/bff/pkg/healthx"
+	"github.com/redis/go-redis/v9"
+)
+
+// InitHealthRegistry 初始化就绪探针的依赖注册表,redis 在这里注册
+// etcd 只有在真正用到的时候才会注册,grpc 下游在创建客户端的时候注册
+func InitHealthRegistry(conf *config.Config, cmd redis.Cmdable) *healthx.Registry {
+	cfg := conf.Health
+
+	reg := healthx.NewRegistry(cfg.Timeout, cfg.NonCritical)
+	reg.Register("redis", healthx.NewRedisChecker(cmd))
+	return reg
+}
+
+// checkNonCritical 检查 health.nonCritical 有没有写错,写错的依赖会一直被当成关键依赖
+// grpc 下游和 etcd 在创建客户端的时候才注册,要等所有 handler 都创建完之后再调用
+func checkNonCritical(reg *healthx.Registry) {
+	if err := reg.CheckNonCritical(); err != nil {
+		panic(fmt.Errorf("health.nonCritical: %w", err))
+	}
+}
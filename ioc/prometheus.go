@@ -1,43 +1,20 @@
 package ioc
 
 import (
+	"github.com/asynccnu/bff/config"
 	"github.com/asynccnu/bff/pkg/prometheusx"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/spf13/viper"
 )
 
 // 感觉划分上不是特别的优雅,但是暂时没更好的办法
-func InitPrometheus() *prometheusx.PrometheusCounter {
-	type PrometheusConfig struct {
-		Namespace string `yaml:"namespace"` //项目名称
-
-		RouterCounter struct {
-			Name string `yaml:"name"`
-			Help string `yaml:"help"`
-		} `yaml:"routerCounter"`
-
-		ActiveConnections struct {
-			Name string `yaml:"name"`
-			Help string `yaml:"help"`
-		} `yaml:"activeConnections"`
-
-		DurationTime struct {
-			Name string `yaml:"name"`
-			Help string `yaml:"help"`
-		} `yaml:"durationTime"`
-	}
-
-	var conf PrometheusConfig
-	// 解析配置文件获取 banner 的位置
-	err := viper.UnmarshalKey("prometheus", &conf)
-	if err != nil {
-		panic(err)
-	}
-
-	p := prometheusx.NewPrometheus(conf.Namespace)
+func InitPrometheus(conf *config.Config) *prometheusx.PrometheusCounter {
+	cfg := conf.Prometheus
+	p := prometheusx.NewPrometheus(cfg.Namespace)
 	return &prometheusx.PrometheusCounter{
-		RouterCounter:     p.RegisterCounter(conf.RouterCounter.Name, conf.RouterCounter.Help, []string{"method", "endpoint", "status"}),
-		ActiveConnections: p.RegisterGauge(conf.ActiveConnections.Name, conf.RouterCounter.Help, []string{"endpoint"}),
-		DurationTime:      p.RegisterHistogram(conf.DurationTime.Name, conf.DurationTime.Help, []string{"endpoint", "status"}, prometheus.DefBuckets),
+		RouterCounter:       p.RegisterCounter(cfg.RouterCounter.Name, cfg.RouterCounter.Help, []string{"method", "endpoint", "status"}),
+		ActiveConnections:   p.RegisterGauge(cfg.ActiveConnections.Name, cfg.RouterCounter.Help, []string{"endpoint"}),
+		DurationTime:        p.RegisterHistogram(cfg.DurationTime.Name, cfg.DurationTime.Help, []string{"endpoint", "status"}, prometheus.DefBuckets),
+		ConfigReloadCounter: p.RegisterCounter(cfg.ConfigReloadCounter.Name, cfg.ConfigReloadCounter.Help, []string{"result"}),
+		DegradedAuthCounter: p.RegisterCounter(cfg.DegradedAuthCounter.Name, cfg.DegradedAuthCounter.Help, []string{"policy", "result"}),
 	}
 }
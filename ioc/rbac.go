@@ -0,0 +1,32 @@
+package ioc
+
+import (
+	"context"
+	"github.com/asynccnu/bff/config"
+	"github.com/asynccnu/bff/pkg/logger"
+	"github.com/asynccnu/bff/web/audit"
+	"github.com/asynccnu/bff/web/middleware"
+	"github.com/asynccnu/bff/web/rbac"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+// InitRBAC 把配置里的角色和授权写进 redis,配置本身在 config.Load 里已经校验过了,
+// redis 暂时连不上只打日志,administrators 里的管理员不依赖 redis,照样可以登录之后通过管理接口补上
+func InitRBAC(conf *config.Config, cmd redis.Cmdable, rt *config.Runtime, l logger.Logger) *rbac.RBAC {
+	r := rbac.NewRBAC(cmd, rt)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := r.Bootstrap(ctx, conf.RBAC); err != nil {
+		l.Error("写入 RBAC 初始配置失败", logger.Error(err))
+	}
+	return r
+}
+
+func InitPermissionMiddleware(r *rbac.RBAC, recorder *audit.Recorder) *middleware.PermissionMiddleware {
+	return middleware.NewPermissionMiddleware(r, recorder)
+}
+
+func InitRBACHandler(r *rbac.RBAC, auth *middleware.PermissionMiddleware) *rbac.RBACHandler {
+	return rbac.NewRBACHandler(r, auth)
+}
@@ -1,22 +1,15 @@
 package ioc
 
 import (
+	"github.com/asynccnu/bff/config"
 	"github.com/redis/go-redis/v9"
-	"github.com/spf13/viper"
 )
 
-func InitRedis() redis.Cmdable {
-	//redis配置
-	type Config struct {
-		Addr     string `yaml:"addr"`
-		Password string `yaml:"password"`
-	}
-	var cfg Config
-	//获取redis配置具体信息
-	err := viper.UnmarshalKey("redis", &cfg)
-	if err != nil {
-		panic(err)
-	}
+func InitRedis(conf *config.Config) (redis.Cmdable, func()) {
+	cfg := conf.Redis
 	//初始化一个redis
-	return redis.NewClient(&redis.Options{Addr: cfg.Addr, Password: cfg.Password})
+	client := redis.NewClient(&redis.Options{Addr: cfg.Addr, Password: cfg.Password})
+	return client, func() {
+		_ = client.Close()
+	}
 }
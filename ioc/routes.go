@@ -0,0 +1,23 @@
+package ioc
+
+import (
+	"github.com/asynccnu/bff/web"
+	"github.com/asynccnu/bff/web/admin"
+	"github.com/gin-gonic/gin"
+	"strings"
+)
+
+// routeTable 生成管理接口展示的路由表,登录要求直接读各个 handler 声明的 RoutePolicy
+// prefix(/api/v1)下没有声明的路由就是必须登录,公开的路由不挂登录中间件,但同样要声明 AuthPublic;
+// prefix 之外的探针和 JWKS 不经过登录中间件,都是公开的
+func routeTable(routes gin.RoutesInfo, prefix string, policy func(method string, fullPath string) web.AuthPolicy) []admin.RouteInfo {
+	res := make([]admin.RouteInfo, 0, len(routes))
+	for _, r := range routes {
+		info := admin.RouteInfo{Method: r.Method, Path: r.Path, Auth: web.AuthPublic}
+		if strings.HasPrefix(r.Path, prefix+"/") {
+			info.Auth = policy(r.Method, r.Path)
+		}
+		res = append(res, info)
+	}
+	return res
+}
@@ -1,31 +0,0 @@
-package ioc
-
-import (
-	"context"
-	staticv1 "github.com/asynccnu/be-api/gen/proto/static/v1"
-	"github.com/go-kratos/kratos/contrib/registry/etcd/v2"
-	"github.com/go-kratos/kratos/v2/transport/grpc"
-	"github.com/spf13/viper"
-	clientv3 "go.etcd.io/etcd/client/v3"
-)
-
-func InitStaticClient(ecli *clientv3.Client) staticv1.StaticServiceClient {
-	type Config struct {
-		Endpoint string `yaml:"endpoint"`
-	}
-	var cfg Config
-	err := viper.UnmarshalKey("grpc.client.static", &cfg)
-	if err != nil {
-		panic(err)
-	}
-	r := etcd.New(ecli)
-	cc, err := grpc.DialInsecure(context.Background(),
-		grpc.WithEndpoint(cfg.Endpoint),
-		grpc.WithDiscovery(r),
-	)
-	if err != nil {
-		panic(err)
-	}
-	client := staticv1.NewStaticServiceClient(cc)
-	return client
-}
@@ -1,7 +1,12 @@
 package ioc
 
 import (
-	"context"
+	"github.com/asynccnu/bff/config"
+	"github.com/asynccnu/bff/pkg/healthx"
+	"github.com/asynccnu/bff/web"
+	"github.com/asynccnu/bff/web/admin"
+	"github.com/asynccnu/bff/web/apikey"
+	"github.com/asynccnu/bff/web/audit"
 	"github.com/asynccnu/bff/web/banner"
 	"github.com/asynccnu/bff/web/calendar"
 	"github.com/asynccnu/bff/web/card"
@@ -11,23 +16,29 @@ import (
 	"github.com/asynccnu/bff/web/feed"
 	"github.com/asynccnu/bff/web/feedback_help"
 	"github.com/asynccnu/bff/web/grade"
+	"github.com/asynccnu/bff/web/health"
 	"github.com/asynccnu/bff/web/infoSum"
 	"github.com/asynccnu/bff/web/metrics"
 	"github.com/asynccnu/bff/web/middleware"
+	"github.com/asynccnu/bff/web/rbac"
 	"github.com/asynccnu/bff/web/static"
 	"github.com/asynccnu/bff/web/tube"
 	"github.com/asynccnu/bff/web/user"
 	"github.com/asynccnu/bff/web/website"
+	"github.com/asynccnu/bff/web/wellknown"
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"time"
+	"net/http"
 )
 
 // 逆天参数数量,依赖注入一堆服务
 func InitGinServer(
+	conf *config.Config,
 	loggerMiddleware *middleware.LoggerMiddleware,
 	loginMiddleware *middleware.LoginMiddleware,
 	corsMiddleware *middleware.CorsMiddleware,
+	rateLimitMiddleware *middleware.RateLimitMiddleware,
+	deadlineMiddleware *middleware.DeadlineMiddleware,
 	tube *tube.TubeHandler,
 	user *user.UserHandler,
 	static *static.StaticHandler,
@@ -43,11 +54,33 @@ func InitGinServer(
 	infoSum *infoSum.InfoSumHandler,
 	card *card.CardHandler,
 	metrics *metrics.MetricsHandler,
+	health *health.HealthHandler,
+	reg *healthx.Registry,
+	admin *admin.AdminHandler,
+	wellKnown *wellknown.WellKnownHandler,
+	rbac *rbac.RBACHandler,
+	apiKey *apikey.ApiKeyHandler,
+	audit *audit.AuditHandler,
 ) *gin.Engine {
 	//初始化一个gin引擎
 	engine := gin.New()
+	//让gin.Context的Deadline/Done沿用Request的context,这样handler直接把ctx传给grpc时路由的超时预算才会生效
+	engine.ContextWithFallback = true
+	//只信任配置中的代理转发过来的X-Forwarded-For,否则限流用的客户端IP可以被随意伪造
+	if len(conf.HTTP.TrustedProxies) > 0 {
+		if err := engine.SetTrustedProxies(conf.HTTP.TrustedProxies); err != nil {
+			panic(err)
+		}
+	}
 	//全局使用gin中间件
 	engine.Use(gin.Recovery())
+
+	//k8s的存活/就绪探针,不经过任何业务中间件,到这里所有下游都已经注册进去了
+	checkNonCritical(reg)
+	health.RegisterRoutes(&engine.RouterGroup, nil)
+	//JWT的公钥,其它服务验证token用
+	wellKnown.RegisterRoutes(&engine.RouterGroup, nil)
+
 	api := engine.Group("/api/v1")
 
 	//在所有的中间件之前进行打点路由的注册(这里是给Prometheus读取用的路由),中间件可能导致其失效所以放在最前面
@@ -61,6 +94,10 @@ func InitGinServer(
 		corsMiddleware.MiddlewareFunc(),
 		//打点和错误处理中间件
 		loggerMiddleware.MiddlewareFunc(),
+		//按IP限流,放在打点之后这样被限流的请求也能正常返回错误
+		rateLimitMiddleware.MiddlewareFunc(),
+		//按路由设置超时预算
+		deadlineMiddleware.MiddlewareFunc(),
 	)
 
 	//创建用户认证中间件
@@ -82,19 +119,25 @@ func InitGinServer(
 	card.RegisterRoute(api, authMiddleware)
 	tube.RegisterRoutes(api, authMiddleware)
 	metrics.RegisterRoutes(api, authMiddleware)
-	//返回路由
-	return engine
-}
+	admin.RegisterRoutes(api, authMiddleware)
+	rbac.RegisterRoutes(api, authMiddleware)
+	apiKey.RegisterRoutes(api, authMiddleware)
+	audit.RegisterRoutes(api, authMiddleware)
 
-func timeout() gin.HandlerFunc {
-	return func(ctx *gin.Context) {
-		_, ok := ctx.Request.Context().Deadline()
-		if !ok {
-			// 强制给一个超时，省得我前端调试等得不耐烦
-			newCtx, cancel := context.WithTimeout(ctx.Request.Context(), time.Second*10)
-			defer cancel()
-			ctx.Request = ctx.Request.Clone(newCtx)
+	//游客可以访问的路由由各个 handler 自己声明,没有声明的挂了登录中间件就必须登录
+	//打点路由注册在登录中间件之前,这里补一个声明,路由表才能如实显示
+	policies := []web.RoutePolicy{{Method: http.MethodGet, Path: "/metrics", Auth: web.AuthPublic}}
+	for _, h := range []any{user, static, banner, department, website, calendar, feed, elecprice, class, feedback, infoSum, grade, card, tube, metrics, admin, rbac, apiKey, audit} {
+		if p, ok := h.(web.AuthPolicies); ok {
+			policies = append(policies, p.AuthPolicies()...)
 		}
-		ctx.Next()
 	}
+	if err := loginMiddleware.AddPolicies(api.BasePath(), policies...); err != nil {
+		panic(err)
+	}
+
+	//路由表要在全部注册完之后才能生成
+	admin.SetRoutes(routeTable(engine.Routes(), api.BasePath(), loginMiddleware.Policy))
+	//返回路由
+	return engine
 }
@@ -1,14 +1,34 @@
 package main
 
 import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/asynccnu/bff/config"
+	"github.com/asynccnu/bff/pkg/logger"
+	"github.com/asynccnu/bff/pkg/tlsx"
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/pflag" // 导入 pflag 包，用于命令行参数解析
 	"github.com/spf13/viper" // 导入 viper 包，用于配置文件解析
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 )
 
 func main() {
 	initViper() // 初始化 viper 以读取配置文件
-	app := InitApp()
+	// 所有配置在这里一次性校验,有问题直接把全部错误打出来退出,而不是在 ioc 里 panic
+	cfg, err := config.Load(viper.GetViper())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "配置校验失败:\n%v\n", err)
+		os.Exit(1)
+	}
+	app, cleanup := InitApp(cfg)
+	// Start 返回时请求已经处理完毕,再关闭 ioc 中创建的 etcd、redis、grpc 等资源
+	defer cleanup()
 	app.Start()
 }
 
@@ -18,6 +38,7 @@ func initViper() {
 	pflag.Parse()                                                   // 解析命令行参数
 	viper.SetConfigType("yaml")                                     // 设置配置文件类型为 YAML
 	viper.SetConfigFile(*cfile)                                     // 设置配置文件路径为解析后的命令行参数值
+	config.BindEnv(viper.GetViper())                                // BFF_ 开头的环境变量可以覆盖配置文件
 	err := viper.ReadInConfig()                                     // 读取配置文件
 	if err != nil {                                                 // 如果读取配置文件时发生错误，则抛出异常
 		panic(err)
@@ -25,17 +46,100 @@ func initViper() {
 }
 
 type App struct {
-	g *gin.Engine
+	g        *gin.Engine
+	l        logger.Logger
+	reloader *config.Reloader
+	cfg      config.HTTPConfig
+	server   *http.Server
 }
 
-func NewApp(g *gin.Engine) *App {
-	return &App{g: g}
+func NewApp(conf *config.Config, g *gin.Engine, l logger.Logger, reloader *config.Reloader) *App {
+	return &App{g: g, l: l, reloader: reloader, cfg: conf.HTTP}
 }
 
+// Start 启动 http 服务并阻塞,直到收到 SIGINT/SIGTERM 或者服务本身出错
+// 收到退出信号后先停止接收新请求,再在 shutdownTimeout 内等待处理中的请求结束
 func (app *App) Start() {
-	addr := viper.GetString("http.addr")
-	err := app.g.Run(addr)
+	cfg := app.cfg
+
+	// 监听配置文件,管理员名单、跨域、限流和日志级别可以不重启直接生效
+	app.reloader.Watch()
+
+	server, err := app.newServer()
 	if err != nil {
+		app.l.Error("http服务初始化失败", logger.Error(err))
+		return
+	}
+	app.server = server
+
+	errCh := make(chan error, 1)
+	go func() {
+		var err error
+		if server.TLSConfig != nil {
+			// 证书由 TLSConfig.GetCertificate 提供,这里不用再传文件,ServeTLS 会自动协商 HTTP/2
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+	}()
+	app.l.Info("http服务已启动",
+		logger.String("addr", cfg.Addr),
+		logger.String("tls", strconv.FormatBool(cfg.TLS.Enabled)),
+		logger.String("h2c", strconv.FormatBool(cfg.H2C)),
+	)
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(quit)
+
+	select {
+	case err := <-errCh:
+		app.l.Error("http服务启动失败", logger.Error(err), logger.String("addr", cfg.Addr))
+		return
+	case sig := <-quit:
+		app.l.Info("收到退出信号,开始优雅退出", logger.String("signal", sig.String()))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	// Shutdown 会先关闭监听,然后等待所有活跃连接处理完毕
+	if err := app.server.Shutdown(ctx); err != nil {
+		app.l.Error("等待请求处理完成超时,强制退出", logger.Error(err))
 		return
 	}
+	app.l.Info("http服务已停止")
+}
+
+// newServer 按照 http 配置创建 server,开启 TLS 时证书文件被替换后新的连接会自动使用新证书
+func (app *App) newServer() (*http.Server, error) {
+	cfg := app.cfg
+	// 开启 h2c 后 gin 的 Handler 会在外面包一层 h2c,明文连接也可以使用 HTTP/2
+	app.g.UseH2C = cfg.H2C
+	server := &http.Server{
+		Addr:              cfg.Addr,
+		Handler:           app.g.Handler(),
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+	if !cfg.TLS.Enabled {
+		return server, nil
+	}
+
+	store, err := tlsx.NewCertStore("", cfg.TLS.CertFile, cfg.TLS.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	server.TLSConfig = &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return store.Certificate(), nil
+		},
+	}
+	return server, nil
 }
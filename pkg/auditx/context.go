@@ -0,0 +1,15 @@
+package auditx
+
+import "github.com/gin-gonic/gin"
+
+const beforeKey = "audit_before"
+
+// SetBefore handler 在修改之前拿得到旧数据的话,放到这里,审计日志会一起记下来
+func SetBefore(ctx *gin.Context, v any) {
+	ctx.Set(beforeKey, v)
+}
+
+// Before 取出 handler 放进来的旧数据
+func Before(ctx *gin.Context) (any, bool) {
+	return ctx.Get(beforeKey)
+}
@@ -0,0 +1,74 @@
+package auditx
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+)
+
+// FileSink 每条日志一行 json 追加到文件末尾,查询时从头扫一遍,只适合量不大的场景
+type FileSink struct {
+	mu   sync.Mutex
+	path string
+	f    *os.File
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &FileSink{path: path, f: f}, nil
+}
+
+func (s *FileSink) Write(ctx context.Context, e Entry) error {
+	val, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.f.Write(append(val, '\n'))
+	return err
+}
+
+func (s *FileSink) Query(ctx context.Context, f Filter) ([]Entry, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var res []Entry
+	scanner := bufio.NewScanner(file)
+	// 一条日志可能带着比较大的请求体
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		// 进程被杀的时候可能留下写了一半的行,跳过就行
+		if json.Unmarshal(scanner.Bytes(), &e) != nil || !f.Match(e) {
+			continue
+		}
+		res = append(res, e)
+		// 文件是按时间顺序写的,只需要保留最后 limit 条
+		if len(res) > f.Limit {
+			res = res[1:]
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	slices.Reverse(res)
+	return res, nil
+}
+
+func (s *FileSink) Close() error {
+	return s.f.Close()
+}
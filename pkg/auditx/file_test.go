@@ -0,0 +1,66 @@
+package auditx
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileSink(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	s, err := NewFileSink(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	base := time.Date(2024, 10, 1, 0, 0, 0, 0, time.Local)
+	entries := []Entry{
+		{Id: "1", Time: base, Actor: "2023000000", Module: "banner"},
+		{Id: "2", Time: base.Add(time.Minute), Actor: "2023000001", Module: "banner"},
+		{Id: "3", Time: base.Add(2 * time.Minute), Actor: "2023000000", Module: "feed"},
+		{Id: "4", Time: base.Add(3 * time.Minute), Actor: "2023000000", Module: "banner"},
+	}
+	for _, e := range entries {
+		if err = s.Write(context.Background(), e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 写了一半的行不影响查询
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	f.WriteString(`{"id":"5","actor":`)
+	f.Close()
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{name: "Newest first", filter: Filter{Limit: 10}, want: []string{"4", "3", "2", "1"}},
+		{name: "Limit keeps newest", filter: Filter{Limit: 2}, want: []string{"4", "3"}},
+		{name: "Actor and module", filter: Filter{Actor: "2023000000", Module: "banner", Limit: 10}, want: []string{"4", "1"}},
+		{name: "Time range", filter: Filter{Start: base.Add(time.Minute), End: base.Add(2 * time.Minute), Limit: 10}, want: []string{"3", "2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := s.Query(context.Background(), tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0, len(res))
+			for _, e := range res {
+				got = append(got, e.Id)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Query() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("Query() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
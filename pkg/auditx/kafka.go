@@ -0,0 +1,31 @@
+package auditx
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/IBM/sarama"
+)
+
+// KafkaSink 写入 kafka,由下游自己消费存档,BFF 这边查不到
+type KafkaSink struct {
+	producer sarama.SyncProducer
+	topic    string
+}
+
+func NewKafkaSink(producer sarama.SyncProducer, topic string) *KafkaSink {
+	return &KafkaSink{producer: producer, topic: topic}
+}
+
+func (s *KafkaSink) Write(ctx context.Context, e Entry) error {
+	val, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	// 同一个人的操作落在同一个分区,消费的时候顺序不会乱
+	_, _, err = s.producer.SendMessage(&sarama.ProducerMessage{
+		Topic: s.topic,
+		Key:   sarama.StringEncoder(e.Actor),
+		Value: sarama.ByteEncoder(val),
+	})
+	return err
+}
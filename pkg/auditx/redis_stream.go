@@ -0,0 +1,69 @@
+package auditx
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+)
+
+// maxScan 一次查询最多翻多少条,条件太宽的时候宁可少返回一些也不能把 redis 拖住
+const maxScan = 10000
+
+// RedisStreamSink 写入 redis stream,stream 的 ID 本身就是毫秒时间戳,按时间范围查询可以直接用 XREVRANGE
+type RedisStreamSink struct {
+	cmd    redis.Cmdable
+	key    string
+	maxLen int64
+}
+
+func NewRedisStreamSink(cmd redis.Cmdable, key string, maxLen int64) *RedisStreamSink {
+	return &RedisStreamSink{cmd: cmd, key: key, maxLen: maxLen}
+}
+
+func (s *RedisStreamSink) Write(ctx context.Context, e Entry) error {
+	val, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return s.cmd.XAdd(ctx, &redis.XAddArgs{
+		Stream: s.key,
+		MaxLen: s.maxLen,
+		Approx: true,
+		Values: []any{"entry", val},
+	}).Err()
+}
+
+func (s *RedisStreamSink) Query(ctx context.Context, f Filter) ([]Entry, error) {
+	start, end := "-", "+"
+	if !f.Start.IsZero() {
+		start = strconv.FormatInt(f.Start.UnixMilli(), 10)
+	}
+	if !f.End.IsZero() {
+		end = strconv.FormatInt(f.End.UnixMilli(), 10)
+	}
+	const batch = 200
+	res := make([]Entry, 0, f.Limit)
+	for scanned := 0; len(res) < f.Limit && scanned < maxScan; scanned += batch {
+		msgs, err := s.cmd.XRevRangeN(ctx, s.key, end, start, batch).Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range msgs {
+			val, _ := msg.Values["entry"].(string)
+			var e Entry
+			if err = json.Unmarshal([]byte(val), &e); err != nil {
+				return nil, err
+			}
+			if f.Match(e) && len(res) < f.Limit {
+				res = append(res, e)
+			}
+		}
+		if len(msgs) < batch {
+			break
+		}
+		// 从上一批最后一条的前面接着往下翻
+		end = "(" + msgs[len(msgs)-1].ID
+	}
+	return res, nil
+}
@@ -0,0 +1,53 @@
+package auditx
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// ErrQueryUnsupported sink 只负责写出去(比如 kafka),没办法按条件查询
+var ErrQueryUnsupported = errors.New("当前的审计日志 sink 不支持查询")
+
+// Entry 一条审计日志
+type Entry struct {
+	Id     string            `json:"id"`
+	Time   time.Time         `json:"time"`
+	Actor  string            `json:"actor"`  // 操作人的学号,API Key 是 apikey:<id>
+	Module string            `json:"module"` // 被操作的模块,例如 banner、rbac
+	Action string            `json:"action"` // 请求方法和路由,例如 POST /api/v1/banner/saveBanner
+	Params map[string]string `json:"params,omitempty"`
+	IP     string            `json:"ip"`
+	Status int               `json:"status"` // 返回的 http 状态码
+	Before json.RawMessage   `json:"before,omitempty"`
+	After  json.RawMessage   `json:"after,omitempty"`
+
+	// 管理员模拟登录时 Actor 是管理员,这里是被模拟的学号
+	Impersonating string `json:"impersonating,omitempty"`
+}
+
+// Filter 查询条件,零值的字段不参与过滤
+type Filter struct {
+	Actor  string
+	Module string
+	Start  time.Time
+	End    time.Time
+	Limit  int
+}
+
+func (f Filter) Match(e Entry) bool {
+	return (f.Actor == "" || e.Actor == f.Actor) &&
+		(f.Module == "" || e.Module == f.Module) &&
+		(f.Start.IsZero() || !e.Time.Before(f.Start)) &&
+		(f.End.IsZero() || !e.Time.After(f.End))
+}
+
+type Sink interface {
+	Write(ctx context.Context, e Entry) error
+}
+
+// Querier 可以查询的 sink 额外实现这个接口,结果按时间倒序
+type Querier interface {
+	Query(ctx context.Context, f Filter) ([]Entry, error)
+}
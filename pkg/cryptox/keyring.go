@@ -0,0 +1,98 @@
+package cryptox
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const KeySize = 32
+
+// Keyring 按 key id 管理一组 AES-256-GCM 密钥
+// 加密总是使用 active 的密钥,并把 key id 写进密文,解密时按密文里的 key id 找密钥,
+// 所以轮换密钥只需要加一个新 key 并切换 active,旧 key 保留到旧数据全部过期为止
+type Keyring struct {
+	active string
+	aeads  map[string]cipher.AEAD
+}
+
+// NewKeyring keys 的值是 base64 编码的 32 字节密钥
+func NewKeyring(active string, keys map[string]string) (*Keyring, error) {
+	if _, ok := keys[active]; !ok {
+		return nil, fmt.Errorf("密钥 %q 不存在", active)
+	}
+	k := &Keyring{active: active, aeads: make(map[string]cipher.AEAD, len(keys))}
+	ids := make([]string, 0, len(keys))
+	for id := range keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	var errs []error
+	for _, id := range ids {
+		aead, err := newAEAD(keys[id])
+		if err != nil {
+			errs = append(errs, fmt.Errorf("密钥 %q: %w", id, err))
+			continue
+		}
+		if strings.Contains(id, ":") {
+			errs = append(errs, fmt.Errorf("密钥 %q: id 不能包含 ':'", id))
+			continue
+		}
+		k.aeads[id] = aead
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return k, nil
+}
+
+func newAEAD(encoded string) (cipher.AEAD, error) {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("不是合法的 base64: %w", err)
+	}
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("长度必须是 %d 字节,实际 %d 字节", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt 返回 <key id>:<base64(nonce|密文)>,aad 参与认证但不加密,解密时必须传入相同的值
+func (k *Keyring) Encrypt(plaintext, aad []byte) (string, error) {
+	aead := k.aeads[k.active]
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, aad)
+	return k.active + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+func (k *Keyring) Decrypt(ciphertext string, aad []byte) ([]byte, error) {
+	id, encoded, ok := strings.Cut(ciphertext, ":")
+	if !ok {
+		return nil, errors.New("密文格式错误")
+	}
+	aead, ok := k.aeads[id]
+	if !ok {
+		return nil, fmt.Errorf("密钥 %q 不存在", id)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize() {
+		return nil, errors.New("密文长度错误")
+	}
+	nonce, sealed := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+	return aead.Open(nil, nonce, sealed, aad)
+}
@@ -0,0 +1,56 @@
+package cryptox
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func key(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), KeySize)))
+}
+
+func TestKeyring(t *testing.T) {
+	old, err := NewKeyring("k1", map[string]string{"k1": key('a')})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ct, err := old.Encrypt([]byte("password"), []byte("ssid"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := NewKeyring("k2", map[string]string{"k1": key('a'), "k2": key('b')})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		ring    *Keyring
+		ct      string
+		aad     string
+		want    string
+		wantErr bool
+	}{
+		{name: "Same keyring", ring: old, ct: ct, aad: "ssid", want: "password"},
+		{name: "Old key kept after rotation", ring: rotated, ct: ct, aad: "ssid", want: "password"},
+		{name: "Wrong aad", ring: old, ct: ct, aad: "other", wantErr: true},
+		{name: "Unknown key id", ring: old, ct: "k9" + ct[2:], aad: "ssid", wantErr: true},
+		{name: "Tampered", ring: old, ct: ct[:len(ct)-4] + "AAAA", aad: "ssid", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.ring.Decrypt(tt.ct, []byte(tt.aad))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Decrypt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Decrypt() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := NewKeyring("k1", map[string]string{"k1": "short"}); err == nil {
+		t.Error("NewKeyring() with invalid key should fail")
+	}
+}
@@ -0,0 +1,240 @@
+package grpcx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/circuitbreaker"
+	"github.com/go-kratos/kratos/v2/middleware/metadata"
+	"github.com/go-kratos/kratos/v2/middleware/recovery"
+	"github.com/go-kratos/kratos/v2/registry"
+	kgrpc "github.com/go-kratos/kratos/v2/transport/grpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/keepalive"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ClientConfig 对应配置文件中 grpc.client.<name> 的一项,每个下游可以单独调整
+type ClientConfig struct {
+	Endpoint   string          `yaml:"endpoint"`   // discovery:///<name> 通过注册中心发现,direct://host:port 直连
+	Addresses  []string        `yaml:"addresses"`  // 静态地址列表,配置后忽略 endpoint,不依赖注册中心
+	RetryCnt   int             `yaml:"retryCnt"`   // 具备重试装饰时的重试次数
+	Timeout    time.Duration   `yaml:"timeout"`    // 单次调用(包括重试)的超时上限,不配置时使用 kratos 默认的 2s,请求本身的 deadline 更短时以请求为准,更长时不会放宽
+	MaxMsgSize int             `yaml:"maxMsgSize"` // 收发消息的最大字节数,不配置时使用 grpc 默认的 4MB
+	Retry      RetryConfig     `yaml:"retry"`
+	Keepalive  KeepaliveConfig `yaml:"keepalive"`
+	Middleware []string        `yaml:"middleware"` // kratos 中间件,按顺序生效,可选值见 middlewares
+	TLS        TLSConfig       `yaml:"tls"`
+	Insecure   bool            `yaml:"insecure"` // 强制使用明文连接,只应该在本地开发时使用
+}
+
+type RetryConfig struct {
+	MaxAttempts       int           `yaml:"maxAttempts"`       // 包括第一次在内的最大尝试次数,小于 2 表示不重试
+	InitialBackoff    time.Duration `yaml:"initialBackoff"`    // 第一次重试前的等待时间
+	MaxBackoff        time.Duration `yaml:"maxBackoff"`        // 等待时间的上限
+	BackoffMultiplier float64       `yaml:"backoffMultiplier"` // 每次重试后等待时间的倍数
+	RetryableCodes    []string      `yaml:"retryableCodes"`    // 可以重试的错误码,例如 UNAVAILABLE
+}
+
+type KeepaliveConfig struct {
+	Time                time.Duration `yaml:"time"`    // 连接空闲多久后发送 ping,为 0 表示不开启
+	Timeout             time.Duration `yaml:"timeout"` // 等待 ping 响应的时间
+	PermitWithoutStream bool          `yaml:"permitWithoutStream"`
+}
+
+// middlewares 可以在配置中按名字引用的客户端中间件
+var middlewares = map[string]func() middleware.Middleware{
+	"recovery":       func() middleware.Middleware { return recovery.Recovery() },
+	"metadata":       func() middleware.Middleware { return metadata.Client() },
+	"circuitbreaker": func() middleware.Middleware { return circuitbreaker.Client() },
+}
+
+// keepalive 的 ping 间隔太短会被服务端当成攻击直接断开连接
+const minKeepaliveTime = 10 * time.Second
+
+// Validate 校验一个下游的配置,prefix 会加在每条错误信息前面,方便定位到具体的配置项
+func (c ClientConfig) Validate(prefix string) error {
+	var errs []error
+	fail := func(key, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s.%s: %s", prefix, key, fmt.Sprintf(format, args...)))
+	}
+
+	if len(c.Addresses) == 0 {
+		if strings.TrimSpace(c.Endpoint) == "" {
+			fail("endpoint", "endpoint 和 addresses 至少配置一个")
+		} else if u, err := url.Parse(c.Endpoint); err != nil || (u.Scheme != SchemeDiscovery && u.Scheme != SchemeDirect) {
+			fail("endpoint", "只支持 %s:///<name> 和 %s://host:port 两种格式", SchemeDiscovery, SchemeDirect)
+		}
+	}
+	for _, addr := range c.Addresses {
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			fail("addresses", "%v", err)
+		}
+	}
+	if c.Timeout < 0 {
+		fail("timeout", "不能为负数")
+	}
+	if c.MaxMsgSize < 0 {
+		fail("maxMsgSize", "不能为负数")
+	}
+
+	if c.Retry.MaxAttempts > 1 {
+		if c.Retry.InitialBackoff <= 0 {
+			fail("retry.initialBackoff", "开启重试时必须大于 0")
+		}
+		if c.Retry.MaxBackoff < c.Retry.InitialBackoff {
+			fail("retry.maxBackoff", "不能小于 initialBackoff")
+		}
+		if c.Retry.BackoffMultiplier < 1 {
+			fail("retry.backoffMultiplier", "不能小于 1")
+		}
+	}
+	for _, name := range c.Retry.RetryableCodes {
+		if _, err := parseCode(name); err != nil {
+			fail("retry.retryableCodes", "%v", err)
+		}
+	}
+
+	if c.Keepalive.Time != 0 && c.Keepalive.Time < minKeepaliveTime {
+		fail("keepalive.time", "不能小于 %s", minKeepaliveTime)
+	}
+
+	for _, name := range c.Middleware {
+		if _, ok := middlewares[name]; !ok {
+			fail("middleware", "不支持的中间件 %q", name)
+		}
+	}
+
+	if c.TLS.Enabled && !c.Insecure {
+		if err := c.TLS.Validate(prefix + ".tls"); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+const (
+	SchemeDiscovery = "discovery"
+	SchemeDirect    = "direct"
+)
+
+// NeedsDiscovery 只有 discovery:/// 形式的地址需要注册中心
+func (c ClientConfig) NeedsDiscovery() bool {
+	return len(c.Addresses) == 0 && strings.HasPrefix(c.Endpoint, SchemeDiscovery+"://")
+}
+
+// Target 返回实际拨号使用的地址
+// kratos 的 direct 解析器要求地址写在 path 里(direct:///host:port),这里兼容一下更直观的 direct://host:port 写法
+func (c ClientConfig) Target() string {
+	if len(c.Addresses) > 0 {
+		return SchemeDirect + ":///" + strings.Join(c.Addresses, ",")
+	}
+	if u, err := url.Parse(c.Endpoint); err == nil && u.Scheme == SchemeDirect && strings.Trim(u.Path, "/") == "" {
+		return SchemeDirect + ":///" + u.Host
+	}
+	return c.Endpoint
+}
+
+// Resolve 返回当前实际会连到的地址,discovery:/// 形式的地址需要去注册中心查一次
+func Resolve(ctx context.Context, cfg ClientConfig, discovery registry.Discovery) ([]string, error) {
+	if !cfg.NeedsDiscovery() {
+		target := strings.TrimPrefix(cfg.Target(), SchemeDirect+":///")
+		return strings.Split(target, ","), nil
+	}
+	if discovery == nil {
+		return nil, fmt.Errorf("%s 需要注册中心", cfg.Endpoint)
+	}
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+	instances, err := discovery.GetService(ctx, strings.Trim(u.Path, "/"))
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]string, 0, len(instances))
+	for _, ins := range instances {
+		addrs = append(addrs, ins.Endpoints...)
+	}
+	return addrs, nil
+}
+
+// Dial 按照配置建立连接,只有 discovery:/// 形式的地址才会用到 discovery,其它情况可以传 nil
+// extra 用来注入日志之类不方便写在配置里的东西
+func Dial(ctx context.Context, cfg ClientConfig, discovery registry.Discovery, extra ...kgrpc.ClientOption) (*grpc.ClientConn, error) {
+	opts := []kgrpc.ClientOption{kgrpc.WithEndpoint(cfg.Target())}
+	if cfg.NeedsDiscovery() {
+		if discovery == nil {
+			return nil, fmt.Errorf("%s 需要注册中心", cfg.Endpoint)
+		}
+		opts = append(opts, kgrpc.WithDiscovery(discovery))
+	}
+	if cfg.Timeout > 0 {
+		opts = append(opts, kgrpc.WithTimeout(cfg.Timeout))
+	}
+
+	mws := make([]middleware.Middleware, 0, len(cfg.Middleware))
+	for _, name := range cfg.Middleware {
+		m, ok := middlewares[name]
+		if !ok {
+			return nil, fmt.Errorf("不支持的中间件 %q", name)
+		}
+		mws = append(mws, m())
+	}
+	if len(mws) > 0 {
+		opts = append(opts, kgrpc.WithMiddleware(mws...))
+	}
+
+	// extra 中的拦截器放在重试之前,这样看到的是包括所有重试在内的整次调用
+	opts = append(opts, extra...)
+
+	// kratos 自己用 service config 配置了负载均衡,这里的重试用拦截器实现,避免把它覆盖掉
+	if cfg.Retry.MaxAttempts > 1 {
+		interceptor, err := RetryInterceptor(cfg.Retry)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, kgrpc.WithUnaryInterceptor(interceptor))
+	}
+
+	var grpcOpts []grpc.DialOption
+	if cfg.Keepalive.Time > 0 {
+		grpcOpts = append(grpcOpts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                cfg.Keepalive.Time,
+			Timeout:             cfg.Keepalive.Timeout,
+			PermitWithoutStream: cfg.Keepalive.PermitWithoutStream,
+		}))
+	}
+	if cfg.MaxMsgSize > 0 {
+		grpcOpts = append(grpcOpts, grpc.WithDefaultCallOptions(
+			grpc.MaxCallRecvMsgSize(cfg.MaxMsgSize),
+			grpc.MaxCallSendMsgSize(cfg.MaxMsgSize),
+		))
+	}
+	if len(grpcOpts) > 0 {
+		opts = append(opts, kgrpc.WithOptions(grpcOpts...))
+	}
+
+	if cfg.Insecure || !cfg.TLS.Enabled {
+		return kgrpc.DialInsecure(ctx, opts...)
+	}
+	tlsConf, err := cfg.TLS.Build()
+	if err != nil {
+		return nil, err
+	}
+	return kgrpc.Dial(ctx, append(opts, kgrpc.WithTLSConfig(tlsConf))...)
+}
+
+// parseCode 把 UNAVAILABLE 这样的名字转换成 codes.Code
+func parseCode(name string) (codes.Code, error) {
+	var code codes.Code
+	if err := code.UnmarshalJSON([]byte(`"` + strings.ToUpper(name) + `"`)); err != nil {
+		return 0, fmt.Errorf("未知的错误码 %q", name)
+	}
+	return code, nil
+}
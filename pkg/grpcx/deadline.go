@@ -0,0 +1,52 @@
+package grpcx
+
+import (
+	"context"
+	"errors"
+	"github.com/asynccnu/bff/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+type budgetKey struct{}
+
+type budget struct {
+	total    time.Duration
+	deadline time.Time
+}
+
+// WithBudget 记录整个请求的时间预算,超时的时候用来计算还剩多少预算
+func WithBudget(ctx context.Context, total time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(ctx, total)
+	deadline, _ := ctx.Deadline()
+	return context.WithValue(ctx, budgetKey{}, budget{total: total, deadline: deadline}), cancel
+}
+
+// TimeoutLogInterceptor 调用超时的时候记录请求剩余的预算
+// 预算还有剩余说明是下游自己的 timeout 配得太小,预算耗尽说明是整个请求给的时间不够
+func TimeoutLogInterceptor(l logger.Logger, name string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if err == nil || (status.Code(err) != codes.DeadlineExceeded && !errors.Is(ctx.Err(), context.DeadlineExceeded)) {
+			return err
+		}
+
+		fields := []logger.Field{
+			logger.Error(err),
+			logger.String("client", name),
+			logger.String("method", method),
+			logger.String("elapsed", time.Since(start).String()),
+		}
+		if b, ok := ctx.Value(budgetKey{}).(budget); ok {
+			fields = append(fields,
+				logger.String("budget", b.total.String()),
+				logger.String("remaining", time.Until(b.deadline).String()),
+			)
+		}
+		l.Warn("grpc调用超时", fields...)
+		return err
+	}
+}
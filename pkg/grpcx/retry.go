@@ -0,0 +1,51 @@
+package grpcx
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"time"
+)
+
+// RetryInterceptor 对可重试的错误码做指数退避重试,所有尝试共用调用方 ctx 的超时时间
+func RetryInterceptor(cfg RetryConfig) (grpc.UnaryClientInterceptor, error) {
+	retryable := make(map[codes.Code]struct{}, len(cfg.RetryableCodes))
+	for _, name := range cfg.RetryableCodes {
+		code, err := parseCode(name)
+		if err != nil {
+			return nil, err
+		}
+		retryable[code] = struct{}{}
+	}
+	// 没有配置的时候只重试 UNAVAILABLE,这种错误说明请求大概率没有到达服务端,重试是安全的
+	if len(retryable) == 0 {
+		retryable[codes.Unavailable] = struct{}{}
+	}
+
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		backoff := cfg.InitialBackoff
+		var err error
+		for attempt := 1; ; attempt++ {
+			err = invoker(ctx, method, req, reply, cc, opts...)
+			if err == nil || attempt >= cfg.MaxAttempts {
+				return err
+			}
+			if _, ok := retryable[status.Code(err)]; !ok {
+				return err
+			}
+
+			timer := time.NewTimer(backoff)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+			backoff = time.Duration(float64(backoff) * cfg.BackoffMultiplier)
+			if backoff > cfg.MaxBackoff {
+				backoff = cfg.MaxBackoff
+			}
+		}
+	}, nil
+}
@@ -0,0 +1,75 @@
+package grpcx
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+	"time"
+)
+
+func TestRetryInterceptor(t *testing.T) {
+	cfg := RetryConfig{
+		MaxAttempts:       3,
+		InitialBackoff:    time.Millisecond,
+		MaxBackoff:        time.Millisecond,
+		BackoffMultiplier: 2,
+	}
+	tests := []struct {
+		name         string
+		errs         []error // 每次调用依次返回的错误
+		wantAttempts int
+		wantCode     codes.Code
+	}{
+		{
+			name:         "Success without retry",
+			errs:         []error{nil},
+			wantAttempts: 1,
+			wantCode:     codes.OK,
+		},
+		{
+			name:         "Retry unavailable until success",
+			errs:         []error{status.Error(codes.Unavailable, ""), nil},
+			wantAttempts: 2,
+			wantCode:     codes.OK,
+		},
+		{
+			name: "Give up after max attempts",
+			errs: []error{
+				status.Error(codes.Unavailable, ""),
+				status.Error(codes.Unavailable, ""),
+				status.Error(codes.Unavailable, ""),
+			},
+			wantAttempts: 3,
+			wantCode:     codes.Unavailable,
+		},
+		{
+			name:         "Non-retryable code",
+			errs:         []error{status.Error(codes.InvalidArgument, "")},
+			wantAttempts: 1,
+			wantCode:     codes.InvalidArgument,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interceptor, err := RetryInterceptor(cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			attempts := 0
+			invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				err := tt.errs[attempts]
+				attempts++
+				return err
+			}
+			err = interceptor(context.Background(), "/test", nil, nil, nil, invoker)
+			if attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", attempts, tt.wantAttempts)
+			}
+			if got := status.Code(err); got != tt.wantCode {
+				t.Errorf("code = %v, want %v", got, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package grpcx
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"github.com/asynccnu/bff/pkg/tlsx"
+	"os"
+)
+
+type TLSConfig struct {
+	Enabled    bool   `yaml:"enabled"`
+	CAFile     string `yaml:"caFile"`   // 校验服务端证书的 CA,不配置时使用系统根证书
+	CertFile   string `yaml:"certFile"` // 客户端证书,和 keyFile 一起配置时开启 mTLS
+	KeyFile    string `yaml:"keyFile"`
+	ServerName string `yaml:"serverName"` // 覆盖证书校验时使用的域名,不配置时使用连接的地址
+}
+
+// IsZero 没有任何配置,用来判断是否需要使用 grpc.tls 中的默认配置
+func (c TLSConfig) IsZero() bool {
+	return c == TLSConfig{}
+}
+
+func (c TLSConfig) Validate(prefix string) error {
+	var errs []error
+	if (c.CertFile == "") != (c.KeyFile == "") {
+		errs = append(errs, fmt.Errorf("%s: certFile 和 keyFile 必须同时配置", prefix))
+	}
+	for _, f := range []struct{ key, file string }{{"caFile", c.CAFile}, {"certFile", c.CertFile}, {"keyFile", c.KeyFile}} {
+		if f.file == "" {
+			continue
+		}
+		if _, err := os.Stat(f.file); err != nil {
+			errs = append(errs, fmt.Errorf("%s.%s: %w", prefix, f.key, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Build 生成给 grpc 使用的 tls.Config,证书文件被替换后下一次握手就会使用新的证书,不需要重启
+func (c TLSConfig) Build() (*tls.Config, error) {
+	store, err := tlsx.NewCertStore(c.CAFile, c.CertFile, c.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	conf := &tls.Config{ServerName: c.ServerName, MinVersion: tls.VersionTLS12}
+	if c.CertFile != "" {
+		conf.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return store.Certificate(), nil
+		}
+	}
+	if c.CAFile != "" {
+		// tls.Config 的 RootCAs 在握手时不能替换,所以跳过默认的校验,在 VerifyConnection 中用最新的 CA 自己校验
+		conf.InsecureSkipVerify = true
+		conf.VerifyConnection = func(cs tls.ConnectionState) error {
+			return verifyPeer(cs, store.RootCAs())
+		}
+	}
+	return conf, nil
+}
+
+func verifyPeer(cs tls.ConnectionState, roots *x509.CertPool) error {
+	if len(cs.PeerCertificates) == 0 {
+		return errors.New("服务端没有提供证书")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range cs.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := cs.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		DNSName:       cs.ServerName,
+	})
+	return err
+}
@@ -0,0 +1,197 @@
+package healthx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Checker 探测一个下游依赖是否可用,返回 nil 表示可用
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CheckerFunc 让普通函数也能作为 Checker 使用
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Result 单个依赖的探测结果
+type Result struct {
+	Status   string `json:"status"` // up 或者 down
+	Critical bool   `json:"critical"`
+	Latency  string `json:"latency"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Report 所有依赖的探测结果汇总
+type Report struct {
+	Status string            `json:"status"` // ok 或者 fail,只由关键依赖决定
+	Checks map[string]Result `json:"checks"`
+}
+
+// Ready 所有关键依赖都可用时返回 true
+func (r Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+type entry struct {
+	name    string
+	checker Checker
+}
+
+// Registry 保存所有需要探测的依赖,由各个 ioc 初始化函数注册进来
+type Registry struct {
+	lock        sync.RWMutex
+	entries     []entry
+	nonCritical map[string]struct{}
+	timeout     time.Duration // 单个依赖的探测超时时间
+}
+
+func NewRegistry(timeout time.Duration, nonCritical []string) *Registry {
+	m := make(map[string]struct{}, len(nonCritical))
+	for _, name := range nonCritical {
+		m[name] = struct{}{}
+	}
+	return &Registry{
+		nonCritical: m,
+		timeout:     timeout,
+	}
+}
+
+// Register 注册一个依赖,同名依赖会被覆盖
+func (r *Registry) Register(name string, checker Checker) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for i := range r.entries {
+		if r.entries[i].name == name {
+			r.entries[i].checker = checker
+			return
+		}
+	}
+	r.entries = append(r.entries, entry{name: name, checker: checker})
+}
+
+// CheckNonCritical 检查 nonCritical 里的名字是不是都注册过了,写错的名字不会报错,对应的依赖却会一直被当成关键依赖
+// 需要在所有依赖都注册完之后调用
+func (r *Registry) CheckNonCritical() error {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	registered := make(map[string]struct{}, len(r.entries))
+	for _, e := range r.entries {
+		registered[e.name] = struct{}{}
+	}
+	var unknown []string
+	for name := range r.nonCritical {
+		if _, ok := registered[name]; !ok {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("没有注册过的依赖: %s", strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+// Check 并发探测所有依赖,非关键依赖失败不会影响整体状态
+func (r *Registry) Check(ctx context.Context) Report {
+	r.lock.RLock()
+	entries := make([]entry, len(r.entries))
+	copy(entries, r.entries)
+	r.lock.RUnlock()
+
+	var (
+		wg   sync.WaitGroup
+		lock sync.Mutex
+	)
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(entries))}
+	for _, e := range entries {
+		wg.Add(1)
+		go func(e entry) {
+			defer wg.Done()
+			_, nonCritical := r.nonCritical[e.name]
+			res := Result{Status: StatusUp, Critical: !nonCritical}
+
+			cctx, cancel := context.WithTimeout(ctx, r.timeout)
+			defer cancel()
+			start := time.Now()
+			err := e.checker.Check(cctx)
+			res.Latency = time.Since(start).String()
+			if err != nil {
+				res.Status = StatusDown
+				res.Error = err.Error()
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			report.Checks[e.name] = res
+			if err != nil && res.Critical {
+				report.Status = StatusFail
+			}
+		}(e)
+	}
+	wg.Wait()
+	return report
+}
+
+// NewRedisChecker 通过 PING 探测 redis
+func NewRedisChecker(cmd redis.Cmdable) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		return cmd.Ping(ctx).Err()
+	})
+}
+
+// NewEtcdChecker 只要有一个 endpoint 能返回状态就认为 etcd 可用
+func NewEtcdChecker(cli *clientv3.Client) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		var err error
+		for _, ep := range cli.Endpoints() {
+			if _, err = cli.Status(ctx, ep); err == nil {
+				return nil
+			}
+		}
+		if err == nil {
+			err = errors.New("没有可用的etcd endpoint")
+		}
+		return err
+	})
+}
+
+// NewGrpcChecker 根据连接状态判断 grpc 下游是否可用
+// 连接处于 Idle 时会主动触发一次连接,并在超时时间内等待它变为 Ready
+func NewGrpcChecker(cc *grpc.ClientConn) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		for {
+			state := cc.GetState()
+			switch state {
+			case connectivity.Ready:
+				return nil
+			case connectivity.Shutdown:
+				return fmt.Errorf("连接状态: %s", state)
+			case connectivity.Idle:
+				cc.Connect()
+			}
+			if !cc.WaitForStateChange(ctx, state) {
+				return fmt.Errorf("连接状态: %s", state)
+			}
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package healthx
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestRegistry_Check(t *testing.T) {
+	up := CheckerFunc(func(ctx context.Context) error { return nil })
+	down := CheckerFunc(func(ctx context.Context) error { return errors.New("down") })
+
+	tests := []struct {
+		name        string
+		nonCritical []string
+		checkers    map[string]Checker
+		wantStatus  string
+	}{
+		{
+			name:       "All up",
+			checkers:   map[string]Checker{"redis": up, "grade": up},
+			wantStatus: StatusOK,
+		},
+		{
+			name:       "Critical down",
+			checkers:   map[string]Checker{"redis": up, "grade": down},
+			wantStatus: StatusFail,
+		},
+		{
+			name:        "Non-critical down",
+			nonCritical: []string{"card"},
+			checkers:    map[string]Checker{"redis": up, "card": down},
+			wantStatus:  StatusOK,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry(time.Second, tt.nonCritical)
+			for name, c := range tt.checkers {
+				r.Register(name, c)
+			}
+			got := r.Check(context.Background())
+			if got.Status != tt.wantStatus {
+				t.Errorf("Registry.Check() status = %v, want %v", got.Status, tt.wantStatus)
+			}
+			if len(got.Checks) != len(tt.checkers) {
+				t.Errorf("Registry.Check() got %d checks, want %d", len(got.Checks), len(tt.checkers))
+			}
+		})
+	}
+}
+
+func TestRegistry_CheckNonCritical(t *testing.T) {
+	up := CheckerFunc(func(ctx context.Context) error { return nil })
+	tests := []struct {
+		name        string
+		nonCritical []string
+		wantErr     bool
+	}{
+		{name: "Registered", nonCritical: []string{"card"}},
+		{name: "None"},
+		{name: "Typo", nonCritical: []string{"card", "elecprise"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry(time.Second, tt.nonCritical)
+			r.Register("redis", up)
+			r.Register("card", up)
+			if err := r.CheckNonCritical(); (err != nil) != tt.wantErr {
+				t.Errorf("Registry.CheckNonCritical() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
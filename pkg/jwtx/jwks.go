@@ -0,0 +1,42 @@
+package jwtx
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+)
+
+// JWKS RFC 7517 定义的公钥集合,其它服务拿它来验证我们签发的 token
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+func newJWK(kid, alg string, key any) JWK {
+	jwk := JWK{Kid: kid, Use: "sig", Alg: alg}
+	enc := base64.RawURLEncoding
+	switch key := key.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = enc.EncodeToString(key.N.Bytes())
+		jwk.E = enc.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = enc.EncodeToString(key)
+	}
+	return jwk
+}
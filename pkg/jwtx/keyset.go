@@ -0,0 +1,184 @@
+package jwtx
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v5"
+	"sort"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// Key 一个非对称密钥,只配置公钥的密钥只用于验签,轮换时用来兼容旧密钥签发的 token
+type Key struct {
+	Kid        string
+	PrivateKey []byte // PEM
+	PublicKey  []byte // PEM,配置了私钥时可以不填
+}
+
+type verifyKey struct {
+	method jwt.SigningMethod
+	key    any
+}
+
+// KeySet 签名和验签用的一组密钥
+// 非对称签名时每个 token 的 header 里带着 kid,验签时按 kid 找公钥,多个公钥同时有效,所以可以不停机轮换:
+// 先把新密钥加进所有实例,再切换 active,旧密钥保留到它签发的 token 全部过期
+type KeySet struct {
+	method  jwt.SigningMethod
+	active  string
+	signKey any
+	keys    map[string]verifyKey
+	// hmac 对称密钥,单独使用时就是原来的 HS256 模式,和非对称密钥一起配置时只用来验证切换之前签发的 token
+	hmac []byte
+}
+
+// NewHMACKeySet HS256 模式,不带 kid
+func NewHMACKeySet(key []byte) *KeySet {
+	return &KeySet{method: jwt.SigningMethodHS256, signKey: key, hmac: key}
+}
+
+// NewKeySet 创建非对称的密钥集合,alg 为 RS256 或 EdDSA,active 是签名使用的密钥,必须配置私钥
+func NewKeySet(alg string, active string, keys []Key) (*KeySet, error) {
+	var method jwt.SigningMethod
+	switch alg {
+	case AlgRS256:
+		method = jwt.SigningMethodRS256
+	case AlgEdDSA:
+		method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("不支持的签名算法 %q", alg)
+	}
+
+	ks := &KeySet{method: method, active: active, keys: make(map[string]verifyKey, len(keys))}
+	var errs []error
+	for _, k := range keys {
+		if k.Kid == "" {
+			errs = append(errs, errors.New("kid 不能为空"))
+			continue
+		}
+		if _, ok := ks.keys[k.Kid]; ok {
+			errs = append(errs, fmt.Errorf("kid %q 重复", k.Kid))
+			continue
+		}
+		priv, pub, err := parseKey(alg, k)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("kid %q: %w", k.Kid, err))
+			continue
+		}
+		ks.keys[k.Kid] = verifyKey{method: method, key: pub}
+		if k.Kid == active {
+			if priv == nil {
+				errs = append(errs, fmt.Errorf("kid %q: 签名用的密钥必须配置私钥", k.Kid))
+				continue
+			}
+			ks.signKey = priv
+		}
+	}
+	if len(errs) == 0 && ks.signKey == nil {
+		errs = append(errs, fmt.Errorf("kid %q 不存在", active))
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return ks, nil
+}
+
+func parseKey(alg string, k Key) (priv crypto.Signer, pub crypto.PublicKey, err error) {
+	if len(k.PrivateKey) == 0 && len(k.PublicKey) == 0 {
+		return nil, nil, errors.New("私钥和公钥至少配置一个")
+	}
+	switch alg {
+	case AlgRS256:
+		if len(k.PrivateKey) > 0 {
+			var key *rsa.PrivateKey
+			if key, err = jwt.ParseRSAPrivateKeyFromPEM(k.PrivateKey); err != nil {
+				return nil, nil, err
+			}
+			return key, key.Public(), nil
+		}
+		pub, err = jwt.ParseRSAPublicKeyFromPEM(k.PublicKey)
+		return nil, pub, err
+	default:
+		if len(k.PrivateKey) > 0 {
+			var key crypto.PrivateKey
+			if key, err = jwt.ParseEdPrivateKeyFromPEM(k.PrivateKey); err != nil {
+				return nil, nil, err
+			}
+			signer := key.(ed25519.PrivateKey)
+			return signer, signer.Public(), nil
+		}
+		pub, err = jwt.ParseEdPublicKeyFromPEM(k.PublicKey)
+		return nil, pub, err
+	}
+}
+
+// WithHMAC 额外接受用 key 签名的 HS256 token,从 HS256 切换到非对称签名时用来兼容已经签发的 token
+func (k *KeySet) WithHMAC(key []byte) *KeySet {
+	k.hmac = key
+	return k
+}
+
+// Sign 使用 active 密钥签名,typ 写在 header 里,用来区分同一组密钥签出来的不同用途的 token
+// HS256 也一样写上,万一两种 token 配置成了同一个密钥,也不能互相冒用
+func (k *KeySet) Sign(claims jwt.Claims, typ string) (string, error) {
+	token := jwt.NewWithClaims(k.method, claims)
+	token.Header["typ"] = typ
+	if k.active != "" {
+		token.Header["kid"] = k.active
+	}
+	return token.SignedString(k.signKey)
+}
+
+// legacyTyp 之前的 HS256 token 的 typ 都是 jwt 库默认的 JWT,没法区分用途
+const legacyTyp = "JWT"
+
+// Keyfunc 给 jwt.Parse 用,会检查 typ,非对称签名的 token 还会检查 kid
+// HS256 的 token 只在 typ 是 JWT 的时候(之前签发的)跳过 typ 检查
+func (k *KeySet) Keyfunc(typ string) jwt.Keyfunc {
+	return func(token *jwt.Token) (any, error) {
+		if token.Method == jwt.SigningMethodHS256 {
+			if k.hmac == nil {
+				return nil, errors.New("不接受 HS256 签名的 token")
+			}
+			if t, _ := token.Header["typ"].(string); t != typ && t != legacyTyp {
+				return nil, fmt.Errorf("token 类型 %q 不匹配,需要 %q", t, typ)
+			}
+			return k.hmac, nil
+		}
+		kid, _ := token.Header["kid"].(string)
+		vk, ok := k.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("未知的 kid %q", kid)
+		}
+		if token.Method != vk.method {
+			return nil, fmt.Errorf("签名算法 %s 和密钥不匹配", token.Method.Alg())
+		}
+		if t, _ := token.Header["typ"].(string); t != typ {
+			return nil, fmt.Errorf("token 类型 %q 不匹配,需要 %q", t, typ)
+		}
+		return vk.key, nil
+	}
+}
+
+// JWKS 所有公钥,HS256 模式下没有可以公开的密钥,返回空列表
+func (k *KeySet) JWKS() JWKS {
+	kids := make([]string, 0, len(k.keys))
+	for kid := range k.keys {
+		kids = append(kids, kid)
+	}
+	sort.Strings(kids)
+	res := JWKS{Keys: make([]JWK, 0, len(kids))}
+	for _, kid := range kids {
+		vk := k.keys[kid]
+		res.Keys = append(res.Keys, newJWK(kid, vk.method.Alg(), vk.key))
+	}
+	return res
+}
@@ -0,0 +1,101 @@
+package jwtx
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/golang-jwt/jwt/v5"
+	"testing"
+)
+
+func pemKey(t *testing.T, key any) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func pemPub(t *testing.T, key any) []byte {
+	der, err := x509.MarshalPKIXPublicKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
+
+func TestKeySet(t *testing.T) {
+	rsaOld, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rsaNew, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	mustKeySet := func(alg, active string, keys ...Key) *KeySet {
+		ks, err := NewKeySet(alg, active, keys)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ks
+	}
+	before := mustKeySet(AlgRS256, "old", Key{Kid: "old", PrivateKey: pemKey(t, rsaOld)})
+	// 轮换之后旧密钥只保留公钥
+	after := mustKeySet(AlgRS256, "new",
+		Key{Kid: "new", PrivateKey: pemKey(t, rsaNew)},
+		Key{Kid: "old", PublicKey: pemPub(t, &rsaOld.PublicKey)},
+	)
+	ed := mustKeySet(AlgEdDSA, "ed", Key{Kid: "ed", PrivateKey: pemKey(t, edKey)})
+	hmac := NewHMACKeySet([]byte("0123456789abcdef0123456789abcdef"))
+
+	tests := []struct {
+		name    string
+		signer  *KeySet
+		typ     string
+		parser  *KeySet
+		wantTyp string
+		wantErr bool
+	}{
+		{name: "RS256", signer: before, typ: "at+jwt", parser: before, wantTyp: "at+jwt"},
+		{name: "Old kid still valid after rotation", signer: before, typ: "at+jwt", parser: after, wantTyp: "at+jwt"},
+		{name: "EdDSA", signer: ed, typ: "at+jwt", parser: ed, wantTyp: "at+jwt"},
+		{name: "Typ mismatch", signer: after, typ: "rt+jwt", parser: after, wantTyp: "at+jwt", wantErr: true},
+		{name: "Unknown kid", signer: ed, typ: "at+jwt", parser: after, wantTyp: "at+jwt", wantErr: true},
+		{name: "HS256 rejected without legacy key", signer: hmac, typ: "at+jwt", parser: after, wantTyp: "at+jwt", wantErr: true},
+		{name: "HS256", signer: hmac, typ: "at+jwt", parser: hmac, wantTyp: "at+jwt"},
+		{name: "HS256 typ mismatch with shared secret", signer: hmac, typ: "rt+jwt", parser: hmac, wantTyp: "at+jwt", wantErr: true},
+		{name: "HS256 token issued before typ", parser: hmac, wantTyp: "at+jwt"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tokenStr string
+			var err error
+			if tt.signer != nil {
+				tokenStr, err = tt.signer.Sign(jwt.RegisteredClaims{Subject: "2023000000"}, tt.typ)
+			} else {
+				// 没有 signer 的用例模拟之前直接用 jwt 库签发的 token
+				tokenStr, err = jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: "2023000000"}).SignedString(tt.parser.hmac)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			var claims jwt.RegisteredClaims
+			_, err = jwt.ParseWithClaims(tokenStr, &claims, tt.parser.Keyfunc(tt.wantTyp))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && claims.Subject != "2023000000" {
+				t.Errorf("Subject = %q", claims.Subject)
+			}
+		})
+	}
+
+	if got := len(after.JWKS().Keys); got != 2 {
+		t.Errorf("JWKS() has %d keys, want 2", got)
+	}
+	if got := len(hmac.JWKS().Keys); got != 0 {
+		t.Errorf("HMAC JWKS() has %d keys, want 0", got)
+	}
+	if _, err := NewKeySet(AlgRS256, "old", []Key{{Kid: "old", PublicKey: pemPub(t, &rsaOld.PublicKey)}}); err == nil {
+		t.Error("NewKeySet() without private key for active kid should fail")
+	}
+}
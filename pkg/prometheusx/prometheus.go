@@ -17,9 +17,11 @@ type Prometheus struct {
 }
 
 type PrometheusCounter struct {
-	RouterCounter     *prometheus.CounterVec
-	ActiveConnections *prometheus.GaugeVec
-	DurationTime      *prometheus.HistogramVec
+	RouterCounter       *prometheus.CounterVec
+	ActiveConnections   *prometheus.GaugeVec
+	DurationTime        *prometheus.HistogramVec
+	ConfigReloadCounter *prometheus.CounterVec
+	DegradedAuthCounter *prometheus.CounterVec
 }
 
 // NewPrometheus 创建一个新的 Prometheus 工具包实例
@@ -0,0 +1,121 @@
+package tlsx
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// CertStore 缓存证书,每次取用时检查文件是否发生变化,变化了就重新加载
+// 只在握手时取用,频率很低,直接 stat 文件的开销可以忽略
+// 证书轮换时文件可能只写了一半,加载失败就继续用旧的,下次再试
+type CertStore struct {
+	caFile   string
+	certFile string
+	keyFile  string
+
+	lock    sync.Mutex
+	stamps  map[string]fileStamp
+	cert    *tls.Certificate
+	rootCAs *x509.CertPool
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewCertStore 不需要的文件传空字符串即可,第一次加载失败会直接返回错误
+func NewCertStore(caFile, certFile, keyFile string) (*CertStore, error) {
+	s := &CertStore{caFile: caFile, certFile: certFile, keyFile: keyFile}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Certificate 当前的证书,可以直接用作 GetCertificate / GetClientCertificate
+func (s *CertStore) Certificate() *tls.Certificate {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.reloadIfChanged()
+	return s.cert
+}
+
+// RootCAs 当前的 CA
+func (s *CertStore) RootCAs() *x509.CertPool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.reloadIfChanged()
+	return s.rootCAs
+}
+
+func (s *CertStore) reloadIfChanged() {
+	if s.changed() {
+		_ = s.load()
+	}
+}
+
+func (s *CertStore) load() error {
+	stamps := make(map[string]fileStamp, 3)
+	for _, file := range s.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		stamps[file] = fileStamp{modTime: info.ModTime(), size: info.Size()}
+	}
+
+	var (
+		cert *tls.Certificate
+		pool *x509.CertPool
+	)
+	if s.caFile != "" {
+		pem, err := os.ReadFile(s.caFile)
+		if err != nil {
+			return fmt.Errorf("读取 CA 证书失败: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("CA 证书 %s 中没有可用的证书", s.caFile)
+		}
+	}
+	if s.certFile != "" {
+		c, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+		if err != nil {
+			return fmt.Errorf("读取证书失败: %w", err)
+		}
+		cert = &c
+	}
+
+	s.stamps, s.cert, s.rootCAs = stamps, cert, pool
+	return nil
+}
+
+func (s *CertStore) changed() bool {
+	for _, file := range s.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return false
+		}
+		if old := s.stamps[file]; !old.modTime.Equal(info.ModTime()) || old.size != info.Size() {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *CertStore) files() []string {
+	files := make([]string, 0, 3)
+	for _, f := range []string{s.caFile, s.certFile, s.keyFile} {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files
+}
@@ -0,0 +1,96 @@
+package admin
+
+import (
+	"context"
+	"github.com/asynccnu/bff/config"
+	"github.com/asynccnu/bff/errs"
+	"github.com/asynccnu/bff/pkg/ginx"
+	"github.com/asynccnu/bff/web"
+	"github.com/asynccnu/bff/web/ijwt"
+	"github.com/asynccnu/bff/web/loginguard"
+	"github.com/asynccnu/bff/web/rbac/perm"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// GrpcResolver 查询每个 grpc 客户端当前解析到的地址
+type GrpcResolver interface {
+	Resolve(ctx context.Context) map[string]GrpcClientInfo
+}
+
+// AdminHandler 给管理员排查问题用的接口
+type AdminHandler struct {
+	conf       *config.Config
+	rt         *config.Runtime
+	resolver   GrpcResolver
+	guard      *loginguard.LoginGuard
+	Authorizer web.Authorizer
+	routes     []RouteInfo
+}
+
+func NewAdminHandler(conf *config.Config, rt *config.Runtime, resolver GrpcResolver, guard *loginguard.LoginGuard, authorizer web.Authorizer) *AdminHandler {
+	return &AdminHandler{conf: conf, rt: rt, resolver: resolver, guard: guard, Authorizer: authorizer}
+}
+
+// SetRoutes 路由表要等所有路由注册完才知道,由 gin 的初始化流程回填
+func (h *AdminHandler) SetRoutes(routes []RouteInfo) {
+	h.routes = routes
+}
+
+func (h *AdminHandler) RegisterRoutes(s *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
+	sg := s.Group("/admin")
+	sg.GET("/info", authMiddleware, h.Authorizer.RequirePermission(perm.AdminRead), ginx.WrapClaims(h.Info))
+	sg.DELETE("/login_lockouts/:studentId", authMiddleware, h.Authorizer.RequirePermission(perm.LoginUnlock), ginx.WrapClaims(h.UnlockLogin))
+}
+
+// AuthPolicies 运维脚本可以用 API Key 查看服务信息、解除登录锁定
+func (h *AdminHandler) AuthPolicies() []web.RoutePolicy {
+	return []web.RoutePolicy{
+		{Method: http.MethodGet, Path: "/admin/info", Auth: web.AuthRequired, Service: true},
+		{Method: http.MethodDelete, Path: "/admin/login_lockouts/:studentId", Auth: web.AuthRequired, Service: true},
+	}
+}
+
+// Info 获取服务运行信息
+// @Summary 获取服务运行信息
+// @Description 返回路由表、构建版本、启动时间、脱敏后的配置以及每个 grpc 客户端当前解析到的地址,需要 admin:read 权限
+// @Tags admin
+// @Produce json
+// @Success 200 {object} web.Response{data=InfoResponse} "成功"
+// @Router /admin/info [get]
+func (h *AdminHandler) Info(ctx *gin.Context, uc ijwt.UserClaims) (web.Response, error) {
+	// 启动时的 runtime 配置可能已经被热更新过了,换成当前生效的那份
+	cfg := h.conf.Redacted()
+	cfg["runtime"] = h.rt.Load().Redacted()
+
+	return web.Response{
+		Msg: "Success",
+		Data: InfoResponse{
+			Build: BuildInfo{
+				Version:   Version,
+				Commit:    commit(),
+				StartTime: startTime,
+			},
+			Routes: h.routes,
+			Config: cfg,
+			Grpc:   h.resolver.Resolve(ctx),
+		},
+	}, nil
+}
+
+// UnlockLogin 解除学号的登录锁定
+// @Summary 解除学号的登录锁定
+// @Description 清空学号的密码错误次数、锁定状态和登录限流窗口,用于处理误伤,需要 login:unlock 权限
+// @Tags admin
+// @Produce json
+// @Param studentId path string true "学号"
+// @Success 200 {object} web.Response "成功"
+// @Router /admin/login_lockouts/{studentId} [delete]
+func (h *AdminHandler) UnlockLogin(ctx *gin.Context, uc ijwt.UserClaims) (web.Response, error) {
+	if err := h.guard.Unlock(ctx, ctx.Param("studentId")); err != nil {
+		return web.Response{}, errs.UNLOCK_LOGIN_ERROR(err)
+	}
+	return web.Response{
+		Msg: "Success",
+	}, nil
+}
@@ -0,0 +1,31 @@
+package admin
+
+import (
+	"github.com/asynccnu/bff/web"
+	"time"
+)
+
+type RouteInfo struct {
+	Method string         `json:"method"`
+	Path   string         `json:"path"`
+	Auth   web.AuthPolicy `json:"auth"` // required/optional/public
+}
+
+type GrpcClientInfo struct {
+	Target    string   `json:"target"`
+	Addresses []string `json:"addresses"`
+	Error     string   `json:"error,omitempty"` // 解析失败的原因,比如注册中心连不上
+}
+
+type BuildInfo struct {
+	Version   string    `json:"version"`
+	Commit    string    `json:"commit"`
+	StartTime time.Time `json:"start_time"`
+}
+
+type InfoResponse struct {
+	Build  BuildInfo                 `json:"build"`
+	Routes []RouteInfo               `json:"routes"`
+	Config map[string]any            `json:"config"`
+	Grpc   map[string]GrpcClientInfo `json:"grpc"`
+}
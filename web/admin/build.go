@@ -0,0 +1,31 @@
+package admin
+
+import (
+	"runtime/debug"
+	"time"
+)
+
+// 构建信息,打包时通过 -ldflags 注入:
+// go build -ldflags "-X github.com/asynccnu/bff/web/admin.Version=v1.2.3 -X github.com/asynccnu/bff/web/admin.Commit=$(git rev-parse HEAD)"
+var (
+	Version = "dev"
+	Commit  = ""
+)
+
+// startTime 进程启动时间,包初始化的时候记录下来
+var startTime = time.Now()
+
+// commit 没有通过 ldflags 注入的时候退回到 go 自己记录的 vcs 信息
+func commit() string {
+	if Commit != "" {
+		return Commit
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" {
+				return s.Value
+			}
+		}
+	}
+	return "unknown"
+}
@@ -0,0 +1,125 @@
+package apikey
+
+import (
+	"errors"
+	"github.com/asynccnu/bff/errs"
+	"github.com/asynccnu/bff/pkg/ginx"
+	"github.com/asynccnu/bff/web"
+	"github.com/asynccnu/bff/web/ijwt"
+	"github.com/asynccnu/bff/web/rbac/perm"
+	"github.com/gin-gonic/gin"
+	"sort"
+	"time"
+)
+
+// ApiKeyHandler 管理内部任务、运维脚本使用的 API Key
+type ApiKeyHandler struct {
+	svc        *Service
+	Authorizer web.Authorizer
+}
+
+func NewApiKeyHandler(svc *Service, authorizer web.Authorizer) *ApiKeyHandler {
+	return &ApiKeyHandler{svc: svc, Authorizer: authorizer}
+}
+
+func (h *ApiKeyHandler) RegisterRoutes(s *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
+	sg := s.Group("/admin/api_keys", authMiddleware, h.Authorizer.RequirePermission(perm.ApiKeyManage))
+	sg.GET("", ginx.WrapClaims(h.GetApiKeys))
+	sg.POST("", ginx.WrapClaimsAndReq(h.CreateApiKey))
+	sg.DELETE("/:id", ginx.WrapClaims(h.DeleteApiKey))
+}
+
+// GetApiKeys 获取所有 API Key
+// @Summary 获取所有 API Key
+// @Description 获取所有 API Key 的信息,不包括明文,已经过期的也会返回
+// @Tags apikey
+// @Produce json
+// @Success 200 {object} web.Response{data=GetApiKeysResp} "成功"
+// @Router /admin/api_keys [get]
+func (h *ApiKeyHandler) GetApiKeys(ctx *gin.Context, uc ijwt.UserClaims) (web.Response, error) {
+	keys, err := h.svc.List(ctx)
+	if err != nil {
+		return web.Response{}, errs.GET_API_KEYS_ERROR(err)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.After(keys[j].CreatedAt) })
+	resp := GetApiKeysResp{ApiKeys: make([]ApiKeyVo, 0, len(keys))}
+	for _, k := range keys {
+		resp.ApiKeys = append(resp.ApiKeys, toVo(k))
+	}
+	return web.Response{
+		Msg:  "Success",
+		Data: resp,
+	}, nil
+}
+
+// CreateApiKey 创建 API Key
+// @Summary 创建 API Key
+// @Description 创建一个 API Key,明文只在这次返回,调用时放在 X-Api-Key 请求头中。scopes 只能是具体的权限,不能是 * 或者 apikey:manage
+// @Tags apikey
+// @Accept json
+// @Produce json
+// @Param request body CreateApiKeyReq true "API Key 信息"
+// @Success 200 {object} web.Response{data=CreateApiKeyResp} "成功"
+// @Router /admin/api_keys [post]
+func (h *ApiKeyHandler) CreateApiKey(ctx *gin.Context, req CreateApiKeyReq, uc ijwt.UserClaims) (web.Response, error) {
+	owner := req.Owner
+	if owner == "" {
+		owner = uc.StudentId
+	}
+	k, raw, err := h.svc.Create(ctx, Key{
+		Name:      req.Name,
+		Owner:     owner,
+		Scopes:    req.Scopes,
+		CreatedBy: uc.StudentId,
+		ExpiresAt: time.Unix(req.ExpiresAt, 0),
+	})
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrInvalidScope), errors.Is(err, ErrInvalidExpiry):
+		return web.Response{}, errs.INVALID_PARAM_VALUE_ERROR(err)
+	default:
+		return web.Response{}, errs.CREATE_API_KEY_ERROR(err)
+	}
+	return web.Response{
+		Msg:  "Success",
+		Data: CreateApiKeyResp{ApiKeyVo: toVo(k), Key: raw},
+	}, nil
+}
+
+// DeleteApiKey 吊销 API Key
+// @Summary 吊销 API Key
+// @Description 吊销 API Key,立即生效
+// @Tags apikey
+// @Produce json
+// @Param id path string true "API Key 的 ID"
+// @Success 200 {object} web.Response "成功"
+// @Router /admin/api_keys/{id} [delete]
+func (h *ApiKeyHandler) DeleteApiKey(ctx *gin.Context, uc ijwt.UserClaims) (web.Response, error) {
+	err := h.svc.Delete(ctx, ctx.Param("id"))
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrKeyNotFound):
+		return web.Response{}, errs.API_KEY_NOT_FOUND_ERROR(err)
+	default:
+		return web.Response{}, errs.DELETE_API_KEY_ERROR(err)
+	}
+	return web.Response{
+		Msg: "Success",
+	}, nil
+}
+
+func toVo(k Key) ApiKeyVo {
+	vo := ApiKeyVo{
+		Id:        k.Id,
+		Name:      k.Name,
+		Owner:     k.Owner,
+		Scopes:    k.Scopes,
+		CreatedBy: k.CreatedBy,
+		CreatedAt: k.CreatedAt.Unix(),
+		ExpiresAt: k.ExpiresAt.Unix(),
+	}
+	if !k.LastUsed.IsZero() {
+		vo.LastUsed = k.LastUsed.Unix()
+	}
+	return vo
+}
@@ -0,0 +1,28 @@
+package apikey
+
+type CreateApiKeyReq struct {
+	Name      string   `json:"name" binding:"required"`
+	Owner     string   `json:"owner"`                         // 负责人的学号,为空时是创建者自己
+	Scopes    []string `json:"scopes" binding:"required"`     // 可以使用的权限,例如 feed:publish
+	ExpiresAt int64    `json:"expires_at" binding:"required"` // 过期时间,unix 秒,最长一年
+}
+
+type CreateApiKeyResp struct {
+	ApiKeyVo
+	Key string `json:"key"` // API Key 明文,只在创建时返回一次,请求时放在 X-Api-Key 请求头中
+}
+
+type ApiKeyVo struct {
+	Id        string   `json:"id"`
+	Name      string   `json:"name"`
+	Owner     string   `json:"owner"`
+	Scopes    []string `json:"scopes"`
+	CreatedBy string   `json:"created_by"`
+	CreatedAt int64    `json:"created_at"`
+	ExpiresAt int64    `json:"expires_at"`
+	LastUsed  int64    `json:"last_used"` // 从未使用过时为 0
+}
+
+type GetApiKeysResp struct {
+	ApiKeys []ApiKeyVo `json:"api_keys"`
+}
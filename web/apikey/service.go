@@ -0,0 +1,175 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/asynccnu/bff/web/ijwt"
+	"github.com/asynccnu/bff/web/rbac/perm"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Header 调用方通过这个请求头携带 API Key,和 Authorization 分开,不会和用户的 token 混在一起
+const Header = "X-Api-Key"
+
+// maxLifetime API Key 最长的有效期,到期之后需要重新创建
+const maxLifetime = 365 * 24 * time.Hour
+
+var (
+	ErrInvalidKey    = errors.New("API Key 无效")
+	ErrKeyNotFound   = errors.New("API Key 不存在")
+	ErrInvalidScope  = errors.New("非法的 scope")
+	ErrInvalidExpiry = errors.New("非法的过期时间")
+)
+
+const (
+	// key ID -> Key(json)
+	keysKey = "ccnubox:apikeys"
+	// key ID -> 最后一次使用的时间(毫秒)
+	lastUsedKey = "ccnubox:apikeys_used"
+)
+
+// Key 保存在 redis 中的 API Key,只保存 secret 的哈希
+type Key struct {
+	Id         string    `json:"id"`
+	Name       string    `json:"name"`
+	Owner      string    `json:"owner"` // 负责人的学号
+	Scopes     []string  `json:"scopes"`
+	SecretHash string    `json:"secret_hash"`
+	CreatedBy  string    `json:"created_by"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	LastUsed   time.Time `json:"-"`
+}
+
+// Claims 把 API Key 映射成 ginx 和权限检查能识别的 UserClaims
+func (k Key) Claims() ijwt.UserClaims {
+	return ijwt.UserClaims{
+		StudentId: "apikey:" + k.Id,
+		ApiKeyId:  k.Id,
+		Scopes:    k.Scopes,
+	}
+}
+
+type Service struct {
+	cmd redis.Cmdable
+}
+
+func NewService(cmd redis.Cmdable) *Service {
+	return &Service{cmd: cmd}
+}
+
+// Create 创建 API Key,返回的明文只有这一次能拿到,格式是 "<id>.<secret>"
+func (s *Service) Create(ctx context.Context, k Key) (Key, string, error) {
+	for _, scope := range k.Scopes {
+		// 服务凭证不能拿到全部权限,不能再去管理别的凭证,也不能冒充学生
+		if scope == perm.All || scope == perm.ApiKeyManage || scope == perm.UserImpersonate || !perm.Known(scope) {
+			return Key{}, "", fmt.Errorf("%w: %q", ErrInvalidScope, scope)
+		}
+	}
+	now := time.Now()
+	if !k.ExpiresAt.After(now) || k.ExpiresAt.Sub(now) > maxLifetime {
+		return Key{}, "", fmt.Errorf("%w: 必须在 %s 以内", ErrInvalidExpiry, maxLifetime)
+	}
+
+	id := make([]byte, 8)
+	secret := make([]byte, 32)
+	if _, err := rand.Read(id); err != nil {
+		return Key{}, "", err
+	}
+	if _, err := rand.Read(secret); err != nil {
+		return Key{}, "", err
+	}
+	k.Id = hex.EncodeToString(id)
+	k.CreatedAt = now
+	raw := base64.RawURLEncoding.EncodeToString(secret)
+	k.SecretHash = hashSecret(raw)
+
+	val, err := json.Marshal(k)
+	if err != nil {
+		return Key{}, "", err
+	}
+	if err = s.cmd.HSet(ctx, keysKey, k.Id, val).Err(); err != nil {
+		return Key{}, "", err
+	}
+	return k, k.Id + "." + raw, nil
+}
+
+// List 所有 API Key,包括已经过期的
+func (s *Service) List(ctx context.Context) ([]Key, error) {
+	vals, err := s.cmd.HGetAll(ctx, keysKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	used, err := s.cmd.HGetAll(ctx, lastUsedKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]Key, 0, len(vals))
+	for id, val := range vals {
+		var k Key
+		if err = json.Unmarshal([]byte(val), &k); err != nil {
+			return nil, err
+		}
+		if ms, err := strconv.ParseInt(used[id], 10, 64); err == nil {
+			k.LastUsed = time.UnixMilli(ms)
+		}
+		keys = append(keys, k)
+	}
+	return keys, nil
+}
+
+// Delete 吊销 API Key,立即生效
+func (s *Service) Delete(ctx context.Context, id string) error {
+	n, err := s.cmd.HDel(ctx, keysKey, id).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrKeyNotFound
+	}
+	return s.cmd.HDel(ctx, lastUsedKey, id).Err()
+}
+
+// Authenticate 校验请求头里的 API Key,成功时返回映射出来的 claims
+func (s *Service) Authenticate(ctx context.Context, raw string) (ijwt.UserClaims, error) {
+	id, secret, ok := strings.Cut(raw, ".")
+	if !ok || id == "" || secret == "" {
+		return ijwt.UserClaims{}, ErrInvalidKey
+	}
+	val, err := s.cmd.HGet(ctx, keysKey, id).Result()
+	switch {
+	case errors.Is(err, redis.Nil):
+		return ijwt.UserClaims{}, ErrInvalidKey
+	case err != nil:
+		return ijwt.UserClaims{}, err
+	}
+	var k Key
+	if err = json.Unmarshal([]byte(val), &k); err != nil {
+		return ijwt.UserClaims{}, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashSecret(secret)), []byte(k.SecretHash)) != 1 {
+		return ijwt.UserClaims{}, ErrInvalidKey
+	}
+	if time.Now().After(k.ExpiresAt) {
+		return ijwt.UserClaims{}, fmt.Errorf("%w: 已于 %s 过期", ErrInvalidKey, k.ExpiresAt.Format(time.DateTime))
+	}
+	// 只是给管理接口展示用的,失败了不影响这次请求
+	s.cmd.HSet(ctx, lastUsedKey, id, time.Now().UnixMilli())
+	return k.Claims(), nil
+}
+
+// hashSecret secret 是 32 字节的随机数,不需要慢哈希,sha256 就足够了
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,102 @@
+package audit
+
+import (
+	"errors"
+	"github.com/asynccnu/bff/errs"
+	"github.com/asynccnu/bff/pkg/auditx"
+	"github.com/asynccnu/bff/pkg/ginx"
+	"github.com/asynccnu/bff/web"
+	"github.com/asynccnu/bff/web/ijwt"
+	"github.com/asynccnu/bff/web/rbac/perm"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"time"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 500
+)
+
+type AuditHandler struct {
+	sink       auditx.Sink
+	Authorizer web.Authorizer
+}
+
+func NewAuditHandler(sink auditx.Sink, authorizer web.Authorizer) *AuditHandler {
+	return &AuditHandler{sink: sink, Authorizer: authorizer}
+}
+
+func (h *AuditHandler) RegisterRoutes(s *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
+	sg := s.Group("/admin/audit_logs", authMiddleware, h.Authorizer.RequirePermission(perm.AuditRead))
+	sg.GET("", ginx.WrapClaimsAndReq(h.GetAuditLogs))
+}
+
+// AuthPolicies 审计日志可以用 API Key 导出
+func (h *AuditHandler) AuthPolicies() []web.RoutePolicy {
+	return []web.RoutePolicy{
+		{Method: http.MethodGet, Path: "/admin/audit_logs", Auth: web.AuthRequired, Service: true},
+	}
+}
+
+// GetAuditLogs 查询审计日志
+// @Summary 查询审计日志
+// @Description 按操作人、模块和时间范围查询管理员的写操作,按时间倒序返回。审计日志写到 kafka 时不支持查询
+// @Tags audit
+// @Produce json
+// @Param actor query string false "操作人的学号,API Key 是 apikey:<id>"
+// @Param module query string false "模块,例如 banner、feed、rbac"
+// @Param start query int false "开始时间,unix 秒"
+// @Param end query int false "结束时间,unix 秒"
+// @Param limit query int false "最多返回多少条,默认 50,最多 500"
+// @Success 200 {object} web.Response{data=GetAuditLogsResp} "成功"
+// @Router /admin/audit_logs [get]
+func (h *AuditHandler) GetAuditLogs(ctx *gin.Context, req GetAuditLogsReq, uc ijwt.UserClaims) (web.Response, error) {
+	q, ok := h.sink.(auditx.Querier)
+	if !ok {
+		return web.Response{}, errs.AUDIT_QUERY_UNSUPPORTED_ERROR(auditx.ErrQueryUnsupported)
+	}
+	f := auditx.Filter{Actor: req.Actor, Module: req.Module, Limit: req.Limit}
+	if req.Start > 0 {
+		f.Start = time.Unix(req.Start, 0)
+	}
+	if req.End > 0 {
+		// 包含 end 这一秒
+		f.End = time.Unix(req.End, 0).Add(time.Second - time.Millisecond)
+	}
+	if !f.Start.IsZero() && !f.End.IsZero() && f.End.Before(f.Start) {
+		return web.Response{}, errs.INVALID_PARAM_VALUE_ERROR(errors.New("结束时间不能早于开始时间"))
+	}
+	switch {
+	case f.Limit <= 0:
+		f.Limit = defaultLimit
+	case f.Limit > maxLimit:
+		f.Limit = maxLimit
+	}
+
+	entries, err := q.Query(ctx, f)
+	if err != nil {
+		return web.Response{}, errs.QUERY_AUDIT_LOGS_ERROR(err)
+	}
+	resp := GetAuditLogsResp{Logs: make([]AuditLogVo, 0, len(entries))}
+	for _, e := range entries {
+		resp.Logs = append(resp.Logs, AuditLogVo{
+			Id:     e.Id,
+			Time:   e.Time.UnixMilli(),
+			Actor:  e.Actor,
+			Module: e.Module,
+			Action: e.Action,
+			Params: e.Params,
+			IP:     e.IP,
+			Status: e.Status,
+			Before: e.Before,
+			After:  e.After,
+
+			Impersonating: e.Impersonating,
+		})
+	}
+	return web.Response{
+		Msg:  "Success",
+		Data: resp,
+	}, nil
+}
@@ -0,0 +1,30 @@
+package audit
+
+import "encoding/json"
+
+type GetAuditLogsReq struct {
+	Actor  string `form:"actor"`
+	Module string `form:"module"`
+	Start  int64  `form:"start"` // unix 秒,包含
+	End    int64  `form:"end"`   // unix 秒,包含
+	Limit  int    `form:"limit"` // 默认 50,最多 500
+}
+
+type AuditLogVo struct {
+	Id     string            `json:"id"`
+	Time   int64             `json:"time"` // unix 毫秒
+	Actor  string            `json:"actor"`
+	Module string            `json:"module"`
+	Action string            `json:"action"`
+	Params map[string]string `json:"params,omitempty"`
+	IP     string            `json:"ip"`
+	Status int               `json:"status"`
+	Before json.RawMessage   `json:"before,omitempty"`
+	After  json.RawMessage   `json:"after,omitempty"`
+
+	Impersonating string `json:"impersonating,omitempty"` // 模拟登录期间的请求,被模拟的学号
+}
+
+type GetAuditLogsResp struct {
+	Logs []AuditLogVo `json:"logs"`
+}
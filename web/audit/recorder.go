@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/asynccnu/bff/pkg/auditx"
+	"github.com/asynccnu/bff/pkg/errorx"
+	"github.com/asynccnu/bff/pkg/ginx"
+	"github.com/asynccnu/bff/pkg/logger"
+	"github.com/asynccnu/bff/web/ijwt"
+	"github.com/gin-gonic/gin"
+	"io"
+	"net/http"
+	"time"
+)
+
+// maxPayload 请求体超过这个大小只保留前面一部分,保存静态资源的请求可能有好几 MB
+const maxPayload = 16 * 1024
+
+// Recorder 记录管理员的写操作,由权限中间件在权限检查通过之后调用,
+// 模拟登录期间的所有请求由登录中间件调用
+type Recorder struct {
+	sink auditx.Sink
+	l    logger.Logger
+}
+
+func NewRecorder(sink auditx.Sink, l logger.Logger) *Recorder {
+	return &Recorder{sink: sink, l: l}
+}
+
+// Record 先执行后面的 handler,再把这次操作写进 sink,写失败只打日志,不影响已经返回的结果
+func (r *Recorder) Record(ctx *gin.Context, module string) {
+	var body []byte
+	if ctx.Request.Body != nil {
+		body, _ = io.ReadAll(ctx.Request.Body)
+		ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	start := time.Now()
+	ctx.Next()
+
+	uc, _ := ginx.GetClaims[ijwt.UserClaims](ctx)
+	e := auditx.Entry{
+		Id:     newId(),
+		Time:   start,
+		Actor:  uc.StudentId,
+		Module: module,
+		Action: ctx.Request.Method + " " + ctx.FullPath(),
+		IP:     ctx.ClientIP(),
+		Status: ctx.Writer.Status(),
+		After:  payload(body),
+	}
+	if uc.IsImpersonated() {
+		e.Actor, e.Impersonating = uc.ImpersonatedBy, uc.StudentId
+	}
+	// 错误响应由外层的日志中间件写出,这里还拿不到真正的状态码
+	if len(ctx.Errors) > 0 {
+		e.Status = http.StatusInternalServerError
+		if ce := errorx.ToCustomError(ctx.Errors.Last().Err); ce != nil {
+			e.Status = ce.HttpCode
+		}
+	}
+	if len(ctx.Params) > 0 {
+		e.Params = make(map[string]string, len(ctx.Params))
+		for _, p := range ctx.Params {
+			e.Params[p.Key] = p.Value
+		}
+	}
+	if v, ok := auditx.Before(ctx); ok {
+		if val, err := json.Marshal(v); err == nil {
+			e.Before = payload(val)
+		}
+	}
+
+	// 请求可能已经超时或者被客户端取消了,审计日志还是要写
+	wctx, cancel := context.WithTimeout(context.WithoutCancel(ctx.Request.Context()), 3*time.Second)
+	defer cancel()
+	if err := r.sink.Write(wctx, e); err != nil {
+		r.l.Error("写入审计日志失败",
+			logger.Error(err),
+			logger.String("actor", e.Actor),
+			logger.String("action", e.Action))
+	}
+}
+
+// payload 合法的 json 原样保存,太大或者不是 json 的转成截断后的字符串
+func payload(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+	if len(body) <= maxPayload && json.Valid(body) {
+		return body
+	}
+	s := string(body)
+	if len(body) > maxPayload {
+		s = string(body[:maxPayload]) + "...(truncated)"
+	}
+	val, _ := json.Marshal(s)
+	return val
+}
+
+func newId() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -1,34 +1,44 @@
 package banner
 
 import (
-	"fmt"
 	bannerv1 "github.com/asynccnu/be-api/gen/proto/banner/v1"
 	"github.com/asynccnu/bff/errs"
 	"github.com/asynccnu/bff/pkg/ginx"
 	"github.com/asynccnu/bff/web"
 	"github.com/asynccnu/bff/web/ijwt"
+	"github.com/asynccnu/bff/web/rbac/perm"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/copier"
+	"net/http"
 )
 
 // BannerHandler 处理与 banner 相关的 API 请求
 type BannerHandler struct {
-	bannerClient   bannerv1.BannerServiceClient
-	Administrators map[string]struct{}
+	bannerClient bannerv1.BannerServiceClient
+	Authorizer   web.Authorizer
 }
 
 // NewBannerHandler 创建一个新的 BannerHandler 实例
 func NewBannerHandler(bannerClient bannerv1.BannerServiceClient,
-	administrators map[string]struct{}) *BannerHandler {
-	return &BannerHandler{bannerClient: bannerClient, Administrators: administrators}
+	authorizer web.Authorizer) *BannerHandler {
+	return &BannerHandler{bannerClient: bannerClient, Authorizer: authorizer}
 }
 
 // RegisterRoutes 注册与 banner 相关的路由
 func (h *BannerHandler) RegisterRoutes(s *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
 	sg := s.Group("/banner")
-	sg.GET("/getBanners", ginx.Wrap(h.GetBanners))
-	sg.POST("/saveBanner", authMiddleware, ginx.WrapClaimsAndReq(h.SaveBanner))
-	sg.DELETE("/delBanner", authMiddleware, ginx.WrapClaimsAndReq(h.DelBanner))
+	sg.GET("/getBanners", authMiddleware, ginx.Wrap(h.GetBanners))
+	sg.POST("/saveBanner", authMiddleware, h.Authorizer.RequirePermission(perm.BannerWrite), ginx.WrapClaimsAndReq(h.SaveBanner))
+	sg.DELETE("/delBanner", authMiddleware, h.Authorizer.RequirePermission(perm.BannerWrite), ginx.WrapClaimsAndReq(h.DelBanner))
+}
+
+// AuthPolicies banner 游客也能看,模拟登录时也放行,增删可以用 API Key 调用
+func (h *BannerHandler) AuthPolicies() []web.RoutePolicy {
+	return []web.RoutePolicy{
+		{Method: http.MethodGet, Path: "/banner/getBanners", Auth: web.AuthOptional, Impersonable: true},
+		{Method: http.MethodPost, Path: "/banner/saveBanner", Auth: web.AuthRequired, Service: true},
+		{Method: http.MethodDelete, Path: "/banner/delBanner", Auth: web.AuthRequired, Service: true},
+	}
 }
 
 // GetBanners 获取 banner 列表
@@ -65,11 +75,6 @@ func (h *BannerHandler) GetBanners(ctx *gin.Context) (web.Response, error) {
 // @Success 200 {object} web.Response "成功"
 // @Router /banner/saveBanner [post]
 func (h *BannerHandler) SaveBanner(ctx *gin.Context, req SaveBannerRequest, uc ijwt.UserClaims) (web.Response, error) {
-
-	if !h.isAdmin(uc.StudentId) {
-		return web.Response{}, errs.ROLE_ERROR(fmt.Errorf("没有访问权限: %s", uc.StudentId))
-	}
-
 	_, err := h.bannerClient.SaveBanner(ctx, &bannerv1.SaveBannerRequest{
 		Id:          req.Id,
 		PictureLink: req.PictureLink,
@@ -94,10 +99,6 @@ func (h *BannerHandler) SaveBanner(ctx *gin.Context, req SaveBannerRequest, uc i
 // @Success 200 {object} web.Response "成功"
 // @Router /banner/delBanner [delete]
 func (h *BannerHandler) DelBanner(ctx *gin.Context, req DelBannerRequest, uc ijwt.UserClaims) (web.Response, error) {
-	if !h.isAdmin(uc.StudentId) {
-		return web.Response{}, errs.ROLE_ERROR(fmt.Errorf("没有访问权限: %s", uc.StudentId))
-	}
-
 	_, err := h.bannerClient.DelBanner(ctx, &bannerv1.DelBannerRequest{Id: req.Id})
 	if err != nil {
 		return web.Response{}, errs.Del_BANNER_ERROR(err)
@@ -107,8 +108,3 @@ func (h *BannerHandler) DelBanner(ctx *gin.Context, req DelBannerRequest, uc ijw
 		Msg: "Success",
 	}, nil
 }
-
-func (h *BannerHandler) isAdmin(studentId string) bool {
-	_, exists := h.Administrators[studentId]
-	return exists
-}
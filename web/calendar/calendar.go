@@ -1,31 +1,41 @@
 package calendar
 
 import (
-	"fmt"
 	calendarv1 "github.com/asynccnu/be-api/gen/proto/calendar/v1"
 	"github.com/asynccnu/bff/errs"
 	"github.com/asynccnu/bff/pkg/ginx"
 	"github.com/asynccnu/bff/web"
 	"github.com/asynccnu/bff/web/ijwt"
+	"github.com/asynccnu/bff/web/rbac/perm"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/copier"
+	"net/http"
 )
 
 type CalendarHandler struct {
 	calendarClient calendarv1.CalendarServiceClient
-	Administrators map[string]struct{}
+	Authorizer     web.Authorizer
 }
 
 func NewCalendarHandler(calendarClient calendarv1.CalendarServiceClient,
-	administrators map[string]struct{}) *CalendarHandler {
-	return &CalendarHandler{calendarClient: calendarClient, Administrators: administrators}
+	authorizer web.Authorizer) *CalendarHandler {
+	return &CalendarHandler{calendarClient: calendarClient, Authorizer: authorizer}
 }
 
 func (h *CalendarHandler) RegisterRoutes(s *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
 	sg := s.Group("/calendar")
-	sg.GET("/getCalendar", ginx.WrapReq(h.GetCalendar))
-	sg.POST("/saveCalendar", authMiddleware, ginx.WrapClaimsAndReq(h.SaveCalendar))
-	sg.DELETE("/delCalendar", authMiddleware, ginx.WrapClaimsAndReq(h.DelCalendar))
+	sg.GET("/getCalendar", authMiddleware, ginx.WrapReq(h.GetCalendar))
+	sg.POST("/saveCalendar", authMiddleware, h.Authorizer.RequirePermission(perm.CalendarWrite), ginx.WrapClaimsAndReq(h.SaveCalendar))
+	sg.DELETE("/delCalendar", authMiddleware, h.Authorizer.RequirePermission(perm.CalendarWrite), ginx.WrapClaimsAndReq(h.DelCalendar))
+}
+
+// AuthPolicies 日历游客也能看,模拟登录时也放行,增删可以用 API Key 调用
+func (h *CalendarHandler) AuthPolicies() []web.RoutePolicy {
+	return []web.RoutePolicy{
+		{Method: http.MethodGet, Path: "/calendar/getCalendar", Auth: web.AuthOptional, Impersonable: true},
+		{Method: http.MethodPost, Path: "/calendar/saveCalendar", Auth: web.AuthRequired, Service: true},
+		{Method: http.MethodDelete, Path: "/calendar/delCalendar", Auth: web.AuthRequired, Service: true},
+	}
 }
 
 // GetCalendars 获取日历列表
@@ -64,10 +74,6 @@ func (h *CalendarHandler) GetCalendar(ctx *gin.Context, req GetCalendarRequest)
 // @Success 200 {object} web.Response "成功"
 // @Router /calendar/saveCalendar [post]
 func (h *CalendarHandler) SaveCalendar(ctx *gin.Context, req SaveCalendarRequest, uc ijwt.UserClaims) (web.Response, error) {
-	if !h.isAdmin(uc.StudentId) {
-		return web.Response{}, errs.ROLE_ERROR(fmt.Errorf("没有访问权限: %s", uc.StudentId))
-	}
-
 	_, err := h.calendarClient.SaveCalendar(ctx, &calendarv1.SaveCalendarRequest{Calendar: &calendarv1.CalendarRequest{
 		Link: req.Link,
 		Year: req.Year,
@@ -92,10 +98,6 @@ func (h *CalendarHandler) SaveCalendar(ctx *gin.Context, req SaveCalendarRequest
 // @Success 200 {object} web.Response "成功"
 // @Router /calendar/delCalendar [delete]
 func (h *CalendarHandler) DelCalendar(ctx *gin.Context, req DelCalendarRequest, uc ijwt.UserClaims) (web.Response, error) {
-	if !h.isAdmin(uc.StudentId) {
-		return web.Response{}, errs.ROLE_ERROR(fmt.Errorf("没有访问权限: %s", uc.StudentId))
-	}
-
 	_, err := h.calendarClient.DelCalendar(ctx, &calendarv1.DelCalendarRequest{Year: req.Year})
 	if err != nil {
 		return web.Response{}, errs.Del_CALENDAR_ERROR(err)
@@ -104,8 +106,3 @@ func (h *CalendarHandler) DelCalendar(ctx *gin.Context, req DelCalendarRequest,
 		Msg: "Success",
 	}, nil
 }
-
-func (h *CalendarHandler) isAdmin(studentId string) bool {
-	_, exists := h.Administrators[studentId]
-	return exists
-}
@@ -8,16 +8,15 @@ import (
 	"github.com/asynccnu/bff/web/ijwt"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/protobuf/ptypes"
+	"net/http"
 )
 
 type CardHandler struct {
-	CardClient     cardv1.CardClient
-	Administrators map[string]struct{} //这里注入的是管理员权限验证配置
+	CardClient cardv1.CardClient
 }
 
-func NewCardHandler(CardClient cardv1.CardClient,
-	administrators map[string]struct{}) *CardHandler {
-	return &CardHandler{CardClient: CardClient, Administrators: administrators}
+func NewCardHandler(CardClient cardv1.CardClient) *CardHandler {
+	return &CardHandler{CardClient: CardClient}
 }
 
 func (h *CardHandler) RegisterRoute(s *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
@@ -27,6 +26,13 @@ func (h *CardHandler) RegisterRoute(s *gin.RouterGroup, authMiddleware gin.Handl
 	sg.POST("/getRecords", authMiddleware, ginx.WrapClaimsAndReq(h.GetRecords))
 }
 
+// AuthPolicies 查询消费记录用的是 POST,模拟登录时也可以访问
+func (h *CardHandler) AuthPolicies() []web.RoutePolicy {
+	return []web.RoutePolicy{
+		{Method: http.MethodPost, Path: "/card/getRecords", Auth: web.AuthRequired, Impersonable: true},
+	}
+}
+
 // @Summary 删除用户的key
 // @Description 记录用户的key
 // @Tags 校园卡
@@ -9,23 +9,21 @@ import (
 	"github.com/asynccnu/bff/web/ijwt"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/copier"
+	"net/http"
 	"time"
 )
 
 type ClassHandler struct {
 	ClassListClient    classlistv1.ClasserClient
 	ClassServiceClinet cs.ClassServiceClient
-	Administrators     map[string]struct{} //这里注入的是管理员权限验证配置
 }
 
 func NewClassListHandler(
 	ClassListClient classlistv1.ClasserClient,
-	ClassServiceClinet cs.ClassServiceClient,
-	administrators map[string]struct{}) *ClassHandler {
+	ClassServiceClinet cs.ClassServiceClient) *ClassHandler {
 	return &ClassHandler{
 		ClassListClient:    ClassListClient,
 		ClassServiceClinet: ClassServiceClinet,
-		Administrators:     administrators,
 	}
 }
 
@@ -41,6 +39,16 @@ func (c *ClassHandler) RegisterRoutes(s *gin.RouterGroup, authMiddleware gin.Han
 	sg.GET("/day/get", authMiddleware, ginx.WrapReq(c.GetSchoolDay))
 }
 
+// AuthPolicies 模拟登录可以查看课表、回收站和课程搜索
+func (c *ClassHandler) AuthPolicies() []web.RoutePolicy {
+	return []web.RoutePolicy{
+		{Method: http.MethodGet, Path: "/class/get", Auth: web.AuthRequired, Impersonable: true},
+		{Method: http.MethodGet, Path: "/class/getRecycle", Auth: web.AuthRequired, Impersonable: true},
+		{Method: http.MethodGet, Path: "/class/search", Auth: web.AuthRequired, Impersonable: true},
+		{Method: http.MethodGet, Path: "/class/day/get", Auth: web.AuthRequired, Impersonable: true},
+	}
+}
+
 // GetClassList 获取课表
 // @Summary 获取课表
 // @Description 根据学期、学年等条件获取课表
@@ -1,31 +1,41 @@
 package department
 
 import (
-	"fmt"
 	departmentv1 "github.com/asynccnu/be-api/gen/proto/department/v1"
 	"github.com/asynccnu/bff/errs"
 	"github.com/asynccnu/bff/pkg/ginx"
 	"github.com/asynccnu/bff/web"
 	"github.com/asynccnu/bff/web/ijwt"
+	"github.com/asynccnu/bff/web/rbac/perm"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/copier"
+	"net/http"
 )
 
 type DepartmentHandler struct {
 	departmentClient departmentv1.DepartmentServiceClient
-	Administrators   map[string]struct{}
+	Authorizer       web.Authorizer
 }
 
 func NewDepartmentHandler(departmentClient departmentv1.DepartmentServiceClient,
-	administrators map[string]struct{}) *DepartmentHandler {
-	return &DepartmentHandler{departmentClient: departmentClient, Administrators: administrators}
+	authorizer web.Authorizer) *DepartmentHandler {
+	return &DepartmentHandler{departmentClient: departmentClient, Authorizer: authorizer}
 }
 
 func (h *DepartmentHandler) RegisterRoutes(s *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
 	sg := s.Group("/department")
 	sg.GET("/getDepartments", ginx.Wrap(h.GetDepartments))
-	sg.POST("/saveDepartment", authMiddleware, ginx.WrapClaimsAndReq(h.SaveDepartment))
-	sg.DELETE("/delDepartment", authMiddleware, ginx.WrapClaimsAndReq(h.DelDepartment))
+	sg.POST("/saveDepartment", authMiddleware, h.Authorizer.RequirePermission(perm.DepartmentWrite), ginx.WrapClaimsAndReq(h.SaveDepartment))
+	sg.DELETE("/delDepartment", authMiddleware, h.Authorizer.RequirePermission(perm.DepartmentWrite), ginx.WrapClaimsAndReq(h.DelDepartment))
+}
+
+// AuthPolicies 部门列表游客也能看,增删可以用 API Key 调用
+func (h *DepartmentHandler) AuthPolicies() []web.RoutePolicy {
+	return []web.RoutePolicy{
+		{Method: http.MethodGet, Path: "/department/getDepartments", Auth: web.AuthPublic},
+		{Method: http.MethodPost, Path: "/department/saveDepartment", Auth: web.AuthRequired, Service: true},
+		{Method: http.MethodDelete, Path: "/department/delDepartment", Auth: web.AuthRequired, Service: true},
+	}
 }
 
 // GetDepartments 获取部门列表
@@ -63,9 +73,6 @@ func (h *DepartmentHandler) GetDepartments(ctx *gin.Context) (web.Response, erro
 // @Success 200 {object} web.Response "成功"
 // @Router /department/saveDepartment [post]
 func (h *DepartmentHandler) SaveDepartment(ctx *gin.Context, req SaveDepartmentRequest, uc ijwt.UserClaims) (web.Response, error) {
-	if !h.isAdmin(uc.StudentId) {
-		return web.Response{}, errs.ROLE_ERROR(fmt.Errorf("没有访问权限: %s", uc.StudentId))
-	}
 	_, err := h.departmentClient.SaveDepartment(ctx, &departmentv1.SaveDepartmentRequest{
 		Department: &departmentv1.Department{
 			Id:    req.Id,
@@ -94,10 +101,6 @@ func (h *DepartmentHandler) SaveDepartment(ctx *gin.Context, req SaveDepartmentR
 // @Success 200 {object} web.Response "成功"
 // @Router /department/delDepartment [delete]
 func (h *DepartmentHandler) DelDepartment(ctx *gin.Context, req DelDepartmentRequest, uc ijwt.UserClaims) (web.Response, error) {
-	if !h.isAdmin(uc.StudentId) {
-		return web.Response{}, errs.ROLE_ERROR(fmt.Errorf("没有访问权限: %s", uc.StudentId))
-	}
-
 	_, err := h.departmentClient.DelDepartment(ctx, &departmentv1.DelDepartmentRequest{Id: req.Id})
 	if err != nil {
 		return web.Response{}, errs.DEL_DEPARTMENT_ERROR(err)
@@ -106,8 +109,3 @@ func (h *DepartmentHandler) DelDepartment(ctx *gin.Context, req DelDepartmentReq
 		Msg: "Success",
 	}, nil
 }
-
-func (h *DepartmentHandler) isAdmin(studentId string) bool {
-	_, exists := h.Administrators[studentId]
-	return exists
-}
@@ -7,18 +7,17 @@ import (
 	"github.com/asynccnu/bff/web"
 	"github.com/asynccnu/bff/web/ijwt"
 	"github.com/gin-gonic/gin"
+	"net/http"
 	"sort"
 	"strconv"
 )
 
 type ElecPriceHandler struct {
 	ElecPriceClient elecpricev1.ElecpriceServiceClient //注入的是grpc服务
-	Administrators  map[string]struct{}                //这里注入的是管理员权限验证配置
 }
 
-func NewElecPriceHandler(elecPriceClient elecpricev1.ElecpriceServiceClient,
-	administrators map[string]struct{}) *ElecPriceHandler {
-	return &ElecPriceHandler{ElecPriceClient: elecPriceClient, Administrators: administrators}
+func NewElecPriceHandler(elecPriceClient elecpricev1.ElecpriceServiceClient) *ElecPriceHandler {
+	return &ElecPriceHandler{ElecPriceClient: elecPriceClient}
 }
 
 func (h *ElecPriceHandler) RegisterRoutes(s *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
@@ -34,6 +33,16 @@ func (h *ElecPriceHandler) RegisterRoutes(s *gin.RouterGroup, authMiddleware gin
 	}
 }
 
+// AuthPolicies 模拟登录可以查询电费和已经设置的提醒
+func (h *ElecPriceHandler) AuthPolicies() []web.RoutePolicy {
+	return []web.RoutePolicy{
+		{Method: http.MethodGet, Path: "/elecprice/getArchitecture", Auth: web.AuthRequired, Impersonable: true},
+		{Method: http.MethodGet, Path: "/elecprice/getRoomInfo", Auth: web.AuthRequired, Impersonable: true},
+		{Method: http.MethodGet, Path: "/elecprice/getPrice", Auth: web.AuthRequired, Impersonable: true},
+		{Method: http.MethodGet, Path: "/elecprice/getStandardList", Auth: web.AuthRequired, Impersonable: true},
+	}
+}
+
 // @Summary 获取楼栋信息
 // @Description 通过区域获取楼栋信息
 // @Tags 电费
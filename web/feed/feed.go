@@ -1,25 +1,26 @@
 package feed
 
 import (
-	"fmt"
 	feedv1 "github.com/asynccnu/be-api/gen/proto/feed/v1"
 	"github.com/asynccnu/bff/errs"
 	"github.com/asynccnu/bff/pkg/ginx"
 	"github.com/asynccnu/bff/web"
 	"github.com/asynccnu/bff/web/ijwt"
+	"github.com/asynccnu/bff/web/rbac/perm"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/copier"
+	"net/http"
 	"time"
 )
 
 type FeedHandler struct {
-	feedClient     feedv1.FeedServiceClient
-	Administrators map[string]struct{}
+	feedClient feedv1.FeedServiceClient
+	Authorizer web.Authorizer
 }
 
 func NewFeedHandler(feedClient feedv1.FeedServiceClient,
-	administrators map[string]struct{}) *FeedHandler {
-	return &FeedHandler{feedClient: feedClient, Administrators: administrators}
+	authorizer web.Authorizer) *FeedHandler {
+	return &FeedHandler{feedClient: feedClient, Authorizer: authorizer}
 }
 
 func (h *FeedHandler) RegisterRoutes(s *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
@@ -31,9 +32,20 @@ func (h *FeedHandler) RegisterRoutes(s *gin.RouterGroup, authMiddleware gin.Hand
 	sg.POST("/readFeedEvent", authMiddleware, ginx.WrapReq(h.ReadFeedEvent))
 	sg.POST("/saveFeedToken", authMiddleware, ginx.WrapClaimsAndReq(h.SaveFeedToken))
 	sg.POST("/removeFeedToken", authMiddleware, ginx.WrapClaimsAndReq(h.RemoveFeedToken))
-	sg.POST("/publicMuxiOfficialMSG", authMiddleware, ginx.WrapClaimsAndReq(h.PublicMuxiOfficialMSG))
-	sg.POST("/stopMuxiOfficialMSG", authMiddleware, ginx.WrapClaimsAndReq(h.StopMuxiOfficialMSG))
-	sg.GET("/getToBePublicOfficialMSG", authMiddleware, ginx.WrapClaims(h.GetToBePublicOfficialMSG))
+	sg.POST("/publicMuxiOfficialMSG", authMiddleware, h.Authorizer.RequirePermission(perm.FeedPublish), ginx.WrapClaimsAndReq(h.PublicMuxiOfficialMSG))
+	sg.POST("/stopMuxiOfficialMSG", authMiddleware, h.Authorizer.RequirePermission(perm.FeedPublish), ginx.WrapClaimsAndReq(h.StopMuxiOfficialMSG))
+	sg.GET("/getToBePublicOfficialMSG", authMiddleware, h.Authorizer.RequirePermission(perm.FeedPublish), ginx.WrapClaims(h.GetToBePublicOfficialMSG))
+}
+
+// AuthPolicies 模拟登录可以查看消息和订阅设置,发布官方消息可以用 API Key 调用
+func (h *FeedHandler) AuthPolicies() []web.RoutePolicy {
+	return []web.RoutePolicy{
+		{Method: http.MethodGet, Path: "/feed/getFeedEvents", Auth: web.AuthRequired, Impersonable: true},
+		{Method: http.MethodGet, Path: "/feed/getFeedAllowList", Auth: web.AuthRequired, Impersonable: true},
+		{Method: http.MethodPost, Path: "/feed/publicMuxiOfficialMSG", Auth: web.AuthRequired, Service: true},
+		{Method: http.MethodPost, Path: "/feed/stopMuxiOfficialMSG", Auth: web.AuthRequired, Service: true},
+		{Method: http.MethodGet, Path: "/feed/getToBePublicOfficialMSG", Auth: web.AuthRequired, Service: true},
+	}
 }
 
 // GetFeedEvents
@@ -232,10 +244,6 @@ func (h *FeedHandler) RemoveFeedToken(ctx *gin.Context, req RemoveFeedTokenReq,
 // @Security BearerAuth
 // @Router /feed/publicMuxiOfficialMSG [post]
 func (h *FeedHandler) PublicMuxiOfficialMSG(ctx *gin.Context, req PublicMuxiOfficialMSGReq, uc ijwt.UserClaims) (web.Response, error) {
-	if !h.isAdmin(uc.StudentId) {
-		return web.Response{}, errs.ROLE_ERROR(fmt.Errorf("没有访问权限: %s", uc.StudentId))
-	}
-
 	_, err := h.feedClient.PublicMuxiOfficialMSG(ctx, &feedv1.PublicMuxiOfficialMSGReq{
 		MuxiOfficialMSG: &feedv1.MuxiOfficialMSG{
 			Title:        req.Title,
@@ -270,9 +278,6 @@ func (h *FeedHandler) PublicMuxiOfficialMSG(ctx *gin.Context, req PublicMuxiOffi
 // @Security BearerAuth
 // @Router /feed/stopMuxiOfficialMSG [post]
 func (h *FeedHandler) StopMuxiOfficialMSG(ctx *gin.Context, req StopMuxiOfficialMSGReq, uc ijwt.UserClaims) (web.Response, error) {
-	if !h.isAdmin(uc.StudentId) {
-		return web.Response{}, errs.ROLE_ERROR(fmt.Errorf("没有访问权限: %s", uc.StudentId))
-	}
 	_, err := h.feedClient.StopMuxiOfficialMSG(ctx, &feedv1.StopMuxiOfficialMSGReq{
 		Id: req.Id,
 	})
@@ -297,10 +302,6 @@ func (h *FeedHandler) StopMuxiOfficialMSG(ctx *gin.Context, req StopMuxiOfficial
 // @Security BearerAuth
 // @Router /feed/getToBePublicOfficialMSG [get]
 func (h *FeedHandler) GetToBePublicOfficialMSG(ctx *gin.Context, uc ijwt.UserClaims) (web.Response, error) {
-	if !h.isAdmin(uc.StudentId) {
-		return web.Response{}, errs.ROLE_ERROR(fmt.Errorf("没有访问权限: %s", uc.StudentId))
-	}
-
 	msgs, err := h.feedClient.GetToBePublicOfficialMSG(ctx, &feedv1.GetToBePublicOfficialMSGReq{})
 	if err != nil {
 		return web.Response{}, errs.GET_TO_BE_PUBLIC_OFFICIAL_MSG_ERROR(err)
@@ -317,8 +318,3 @@ func (h *FeedHandler) GetToBePublicOfficialMSG(ctx *gin.Context, uc ijwt.UserCla
 		Data: response,
 	}, nil
 }
-
-func (h *FeedHandler) isAdmin(studentId string) bool {
-	_, exists := h.Administrators[studentId]
-	return exists
-}
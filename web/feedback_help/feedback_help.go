@@ -1,35 +1,47 @@
 package feedback_help
 
 import (
-	"fmt"
 	feedback_helpv1 "github.com/asynccnu/be-api/gen/proto/feedback_help/v1"
 	"github.com/asynccnu/bff/errs"
 	"github.com/asynccnu/bff/pkg/ginx"
 	"github.com/asynccnu/bff/web"
 	"github.com/asynccnu/bff/web/ijwt"
+	"github.com/asynccnu/bff/web/rbac/perm"
 	"github.com/gin-gonic/gin"
+	"net/http"
 )
 
 type FeedbackHelpHandler struct {
 	FeedbackHelpClient feedback_helpv1.FeedbackHelpClient //注入的是grpc服务
-	Administrators     map[string]struct{}                //这里注入的是管理员权限验证配置
+	Authorizer         web.Authorizer                     //这里注入的是管理员权限验证配置
 }
 
 func NewFeedbackHelpHandler(FeedbackHelpClient feedback_helpv1.FeedbackHelpClient,
-	administrators map[string]struct{}) *FeedbackHelpHandler {
-	return &FeedbackHelpHandler{FeedbackHelpClient: FeedbackHelpClient, Administrators: administrators}
+	authorizer web.Authorizer) *FeedbackHelpHandler {
+	return &FeedbackHelpHandler{FeedbackHelpClient: FeedbackHelpClient, Authorizer: authorizer}
 }
 
 func (h *FeedbackHelpHandler) RegisterRoutes(s *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
 	sg := s.Group("/feedback_help")
 	sg.GET("/getQuestion", authMiddleware, ginx.Wrap(h.GetQuestions))
-	sg.POST("/createQuestion", authMiddleware, ginx.WrapClaimsAndReq(h.CreateQuestion))
-	sg.POST("/changeQuestion", authMiddleware, ginx.WrapClaimsAndReq(h.ChangeQuestion))
-	sg.POST("/deleteQuestion", authMiddleware, ginx.WrapClaimsAndReq(h.DeleteQuestion))
+	sg.POST("/createQuestion", authMiddleware, h.Authorizer.RequirePermission(perm.FAQWrite), ginx.WrapClaimsAndReq(h.CreateQuestion))
+	sg.POST("/changeQuestion", authMiddleware, h.Authorizer.RequirePermission(perm.FAQWrite), ginx.WrapClaimsAndReq(h.ChangeQuestion))
+	sg.POST("/deleteQuestion", authMiddleware, h.Authorizer.RequirePermission(perm.FAQWrite), ginx.WrapClaimsAndReq(h.DeleteQuestion))
 	sg.GET("/findQuestionsByName", authMiddleware, ginx.WrapReq(h.FindQuestionsByName))
 	sg.POST("/noteQuestion", authMiddleware, ginx.WrapReq(h.NoteQuestion))
 }
 
+// AuthPolicies 模拟登录可以查看常见问题,维护问题可以用 API Key 调用
+func (h *FeedbackHelpHandler) AuthPolicies() []web.RoutePolicy {
+	return []web.RoutePolicy{
+		{Method: http.MethodGet, Path: "/feedback_help/getQuestion", Auth: web.AuthRequired, Impersonable: true},
+		{Method: http.MethodGet, Path: "/feedback_help/findQuestionsByName", Auth: web.AuthRequired, Impersonable: true},
+		{Method: http.MethodPost, Path: "/feedback_help/createQuestion", Auth: web.AuthRequired, Service: true},
+		{Method: http.MethodPost, Path: "/feedback_help/changeQuestion", Auth: web.AuthRequired, Service: true},
+		{Method: http.MethodPost, Path: "/feedback_help/deleteQuestion", Auth: web.AuthRequired, Service: true},
+	}
+}
+
 // @Summary 获取常见问题
 // @Description 获取点击数量最多的10个常见问题
 // @Tags 帮助与反馈
@@ -72,10 +84,6 @@ func (h *FeedbackHelpHandler) GetQuestions(c *gin.Context) (web.Response, error)
 // @Failure 500 {object} web.Response "系统异常"
 // @Router /feedback_help/createQuestion [post]
 func (h *FeedbackHelpHandler) CreateQuestion(c *gin.Context, req CreateQuestionReq, uc ijwt.UserClaims) (web.Response, error) {
-	if !h.isAdmin(uc.StudentId) {
-		return web.Response{}, errs.ROLE_ERROR(fmt.Errorf("没有访问权限: %s", uc.StudentId))
-	}
-
 	_, err := h.FeedbackHelpClient.CreateQuestion(c, &feedback_helpv1.CreateQuestionRequest{
 		Question: req.Question,
 		Anwser:   req.Answer,
@@ -99,10 +107,6 @@ func (h *FeedbackHelpHandler) CreateQuestion(c *gin.Context, req CreateQuestionR
 // @Failure 500 {object} web.Response "系统异常"
 // @Router /feedback_help/changeQuestion [post]
 func (h *FeedbackHelpHandler) ChangeQuestion(c *gin.Context, req ChangeQuestionReq, uc ijwt.UserClaims) (web.Response, error) {
-	if !h.isAdmin(uc.StudentId) {
-		return web.Response{}, errs.ROLE_ERROR(fmt.Errorf("没有访问权限: %s", uc.StudentId))
-	}
-
 	_, err := h.FeedbackHelpClient.ChangeQuestion(c, &feedback_helpv1.UpdateQuestionRequest{
 		QuestionId: req.QuestionId,
 		Question:   req.Question,
@@ -127,10 +131,6 @@ func (h *FeedbackHelpHandler) ChangeQuestion(c *gin.Context, req ChangeQuestionR
 // @Failure 500 {object} web.Response "系统异常"
 // @Router /feedback_help/deleteQuestion [post]
 func (h *FeedbackHelpHandler) DeleteQuestion(c *gin.Context, req DeleteQuestionReq, uc ijwt.UserClaims) (web.Response, error) {
-	if !h.isAdmin(uc.StudentId) {
-		return web.Response{}, errs.ROLE_ERROR(fmt.Errorf("没有访问权限: %s", uc.StudentId))
-	}
-
 	_, err := h.FeedbackHelpClient.DeleteQuestion(c, &feedback_helpv1.DeleteQuestionRequest{
 		QuestionId: req.QuestionId,
 	})
@@ -203,7 +203,3 @@ func (h *FeedbackHelpHandler) NoteQuestion(c *gin.Context, req NoteQuestionReq)
 		Msg: "Success",
 	}, nil
 }
-func (h *FeedbackHelpHandler) isAdmin(studentId string) bool {
-	_, exists := h.Administrators[studentId]
-	return exists
-}
@@ -11,25 +11,23 @@ import (
 	"github.com/asynccnu/bff/web"
 	"github.com/asynccnu/bff/web/ijwt"
 	"github.com/gin-gonic/gin"
+	"net/http"
 )
 
 type GradeHandler struct {
-	GradeClient    gradev1.GradeServiceClient //注入的是grpc服务
-	CounterClient  counterv1.CounterServiceClient
-	Administrators map[string]struct{} //这里注入的是管理员权限验证配置
-	l              logger.Logger
+	GradeClient   gradev1.GradeServiceClient //注入的是grpc服务
+	CounterClient counterv1.CounterServiceClient
+	l             logger.Logger
 }
 
 func NewGradeHandler(
 	GradeClient gradev1.GradeServiceClient, //注入的是grpc服务
 	CounterClient counterv1.CounterServiceClient,
-	l logger.Logger,
-	administrators map[string]struct{}) *GradeHandler {
+	l logger.Logger) *GradeHandler {
 	return &GradeHandler{
-		GradeClient:    GradeClient,
-		CounterClient:  CounterClient,
-		Administrators: administrators,
-		l:              l,
+		GradeClient:   GradeClient,
+		CounterClient: CounterClient,
+		l:             l,
 	}
 }
 
@@ -41,6 +39,14 @@ func (h *GradeHandler) RegisterRoutes(s *gin.RouterGroup, authMiddleware gin.Han
 
 }
 
+// AuthPolicies 模拟登录可以查看成绩
+func (h *GradeHandler) AuthPolicies() []web.RoutePolicy {
+	return []web.RoutePolicy{
+		{Method: http.MethodGet, Path: "/grade/getGradeByTerm", Auth: web.AuthRequired, Impersonable: true},
+		{Method: http.MethodGet, Path: "/grade/getGradeScore", Auth: web.AuthRequired, Impersonable: true},
+	}
+}
+
 // GradeByTerm 查询按学年和学期的成绩
 // @Summary 查询按学年和学期的成绩
 // @Description 根据学年号和学期号获取用户的成绩
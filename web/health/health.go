@@ -0,0 +1,48 @@
+package health
+
+import (
+	"github.com/asynccnu/bff/pkg/healthx"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// HealthHandler 给 k8s 使用的存活/就绪探针
+type HealthHandler struct {
+	registry *healthx.Registry
+}
+
+func NewHealthHandler(registry *healthx.Registry) *HealthHandler {
+	return &HealthHandler{registry: registry}
+}
+
+// RegisterRoutes 探针不走鉴权和日志中间件,直接挂在根路由上
+func (h *HealthHandler) RegisterRoutes(s *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
+	s.GET("/healthz", h.Healthz)
+	s.GET("/readyz", h.Readyz)
+}
+
+// Healthz 进程存活即返回 200
+// @Summary 存活探针
+// @Description 只要进程还能处理请求就返回 200,不检查下游依赖
+// @Tags 健康检查
+// @Success 200 {object} map[string]string "成功"
+// @Router /healthz [get]
+func (h *HealthHandler) Healthz(ctx *gin.Context) {
+	ctx.JSON(http.StatusOK, gin.H{"status": healthx.StatusOK})
+}
+
+// Readyz 检查 redis、etcd 以及所有 grpc 下游的状态
+// @Summary 就绪探针
+// @Description 逐个探测下游依赖并返回结果,任一关键依赖不可用时返回 503
+// @Tags 健康检查
+// @Success 200 {object} healthx.Report "所有关键依赖可用"
+// @Failure 503 {object} healthx.Report "存在不可用的关键依赖"
+// @Router /readyz [get]
+func (h *HealthHandler) Readyz(ctx *gin.Context) {
+	report := h.registry.Check(ctx)
+	if !report.Ready() {
+		ctx.JSON(http.StatusServiceUnavailable, report)
+		return
+	}
+	ctx.JSON(http.StatusOK, report)
+}
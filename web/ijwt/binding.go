@@ -0,0 +1,138 @@
+package ijwt
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"github.com/asynccnu/bff/config"
+	"github.com/gin-gonic/gin"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 设备绑定相关的请求头
+const (
+	HeaderClientType      = "X-Client-Type"
+	HeaderDeviceId        = "X-Device-Id"        // 客户端安装时生成并持久化的设备 ID,应用升级、系统升级都不会变
+	HeaderDeviceKey       = "X-Device-Key"       // 登录时上报,安装时生成的 ed25519 公钥,base64 编码
+	HeaderDeviceTimestamp = "X-Device-Timestamp" // 签名时间,unix 秒
+	HeaderDeviceSignature = "X-Device-Signature" // 对 "<timestamp>\n<method>\n<path>" 的签名,base64 编码
+)
+
+// maxSignatureSkew 签名时间和服务器时间允许的最大误差
+const maxSignatureSkew = 5 * time.Minute
+
+// ErrDeviceBinding 请求和 token 绑定的设备对不上,或者登录时没有提供策略要求的设备信息
+var ErrDeviceBinding = errors.New("设备校验失败")
+
+// Device 会话绑定的设备,登录时写进 token,之后每个请求都要和它对上
+type Device struct {
+	ClientType string `json:",omitempty"`
+	DeviceId   string `json:",omitempty"`
+	DeviceKey  string `json:",omitempty"`
+}
+
+// DeviceBinding 按客户端类型选择 token 和设备的绑定方式
+type DeviceBinding struct {
+	defaultClientType string
+	policies          map[string]string
+}
+
+func NewDeviceBinding(cfg config.DeviceBindingConfig) *DeviceBinding {
+	return &DeviceBinding{defaultClientType: strings.ToLower(cfg.DefaultClientType), policies: cfg.Policies}
+}
+
+func (b *DeviceBinding) clientType(ctx *gin.Context) string {
+	if t := strings.ToLower(ctx.GetHeader(HeaderClientType)); t != "" {
+		return t
+	}
+	return b.defaultClientType
+}
+
+// policy 没有单独配置的客户端类型沿用原来的 User-Agent 校验
+func (b *DeviceBinding) policy(clientType string) string {
+	if p, ok := b.policies[clientType]; ok {
+		return p
+	}
+	return config.DeviceBindingUserAgent
+}
+
+// Bind 登录时读取请求中的设备信息
+func (b *DeviceBinding) Bind(ctx *gin.Context) (Device, error) {
+	d := Device{
+		ClientType: b.clientType(ctx),
+		DeviceId:   ctx.GetHeader(HeaderDeviceId),
+		DeviceKey:  ctx.GetHeader(HeaderDeviceKey),
+	}
+	if len(d.DeviceId) > 128 {
+		return Device{}, fmt.Errorf("%w: %s 过长", ErrDeviceBinding, HeaderDeviceId)
+	}
+	if d.DeviceKey != "" {
+		if key, err := base64.StdEncoding.DecodeString(d.DeviceKey); err != nil || len(key) != ed25519.PublicKeySize {
+			return Device{}, fmt.Errorf("%w: %s 不是合法的 ed25519 公钥", ErrDeviceBinding, HeaderDeviceKey)
+		}
+		if d.DeviceId == "" {
+			return Device{}, fmt.Errorf("%w: 上报 %s 时必须同时提供 %s", ErrDeviceBinding, HeaderDeviceKey, HeaderDeviceId)
+		}
+	}
+	if b.policy(d.ClientType) == config.DeviceBindingSigned && d.DeviceKey == "" {
+		return Device{}, fmt.Errorf("%w: %s 客户端登录时必须提供 %s", ErrDeviceBinding, d.ClientType, HeaderDeviceKey)
+	}
+	return d, nil
+}
+
+// Verify 检查请求是否来自 token 绑定的设备,userAgent 是登录时记录的 User-Agent
+func (b *DeviceBinding) Verify(ctx *gin.Context, userAgent string, d Device) error {
+	policy := b.policy(d.ClientType)
+	// 没有设备 ID 的 token 是旧版本客户端或者这个功能上线之前签发的,只能继续比较 User-Agent
+	if d.DeviceId == "" && policy != config.DeviceBindingNone {
+		policy = config.DeviceBindingUserAgent
+	}
+
+	switch policy {
+	case config.DeviceBindingNone:
+		return nil
+	case config.DeviceBindingUserAgent:
+		if userAgent != ctx.GetHeader("User-Agent") {
+			// 大概率是攻击者才会进入这个分支
+			return fmt.Errorf("%w: User-Agent 不一致", ErrDeviceBinding)
+		}
+		return nil
+	}
+
+	if ctx.GetHeader(HeaderDeviceId) != d.DeviceId {
+		return fmt.Errorf("%w: 设备 ID 不一致", ErrDeviceBinding)
+	}
+	// 登录时上报过公钥的安装必须每个请求都签名
+	if d.DeviceKey != "" {
+		return verifySignature(ctx, d.DeviceKey)
+	}
+	return nil
+}
+
+func verifySignature(ctx *gin.Context, deviceKey string) error {
+	ts, err := strconv.ParseInt(ctx.GetHeader(HeaderDeviceTimestamp), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: 缺少 %s", ErrDeviceBinding, HeaderDeviceTimestamp)
+	}
+	if skew := time.Since(time.Unix(ts, 0)); skew > maxSignatureSkew || skew < -maxSignatureSkew {
+		return fmt.Errorf("%w: 签名已过期", ErrDeviceBinding)
+	}
+	sig, err := base64.StdEncoding.DecodeString(ctx.GetHeader(HeaderDeviceSignature))
+	if err != nil {
+		return fmt.Errorf("%w: %s 格式错误", ErrDeviceBinding, HeaderDeviceSignature)
+	}
+	key, _ := base64.StdEncoding.DecodeString(deviceKey)
+	msg := SigningPayload(ctx.GetHeader(HeaderDeviceTimestamp), ctx.Request.Method, ctx.Request.URL.RequestURI())
+	if len(key) != ed25519.PublicKeySize || !ed25519.Verify(key, msg, sig) {
+		return fmt.Errorf("%w: 签名错误", ErrDeviceBinding)
+	}
+	return nil
+}
+
+// SigningPayload 客户端需要签名的内容,path 包括查询参数
+func SigningPayload(timestamp, method, path string) []byte {
+	return []byte(timestamp + "\n" + method + "\n" + path)
+}
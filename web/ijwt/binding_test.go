@@ -0,0 +1,63 @@
+package ijwt
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"github.com/asynccnu/bff/config"
+	"github.com/gin-gonic/gin"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestDeviceBindingVerify(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := NewDeviceBinding(config.DeviceBindingConfig{
+		DefaultClientType: "app",
+		Policies:          map[string]string{"app": config.DeviceBindingDevice, "web": config.DeviceBindingUserAgent},
+	})
+	signed := Device{ClientType: "app", DeviceId: "d1", DeviceKey: base64.StdEncoding.EncodeToString(pub)}
+	sign := func(h map[string]string, ts time.Time) {
+		h[HeaderDeviceTimestamp] = strconv.FormatInt(ts.Unix(), 10)
+		msg := SigningPayload(h[HeaderDeviceTimestamp], "GET", "/users/sessions?x=1")
+		h[HeaderDeviceSignature] = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, msg))
+	}
+
+	tests := []struct {
+		name    string
+		device  Device
+		headers map[string]string
+		sign    time.Time // 非零时用设备私钥签名
+		wantErr bool
+	}{
+		{name: "Same device after app upgrade", device: Device{ClientType: "app", DeviceId: "d1"}, headers: map[string]string{HeaderDeviceId: "d1", "User-Agent": "app/2.0"}},
+		{name: "Different device", device: Device{ClientType: "app", DeviceId: "d1"}, headers: map[string]string{HeaderDeviceId: "d2", "User-Agent": "app/1.0"}, wantErr: true},
+		{name: "Legacy token falls back to User-Agent", headers: map[string]string{"User-Agent": "app/2.0"}, wantErr: true},
+		{name: "Web client checks User-Agent", device: Device{ClientType: "web", DeviceId: "d1"}, headers: map[string]string{"User-Agent": "app/1.0"}},
+		{name: "Valid signature", device: signed, headers: map[string]string{HeaderDeviceId: "d1"}, sign: time.Now()},
+		{name: "Stale signature", device: signed, headers: map[string]string{HeaderDeviceId: "d1"}, sign: time.Now().Add(-time.Hour), wantErr: true},
+		{name: "Missing signature", device: signed, headers: map[string]string{HeaderDeviceId: "d1"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.sign.IsZero() {
+				sign(tt.headers, tt.sign)
+			}
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest("GET", "/users/sessions?x=1", nil)
+			for k, v := range tt.headers {
+				ctx.Request.Header.Set(k, v)
+			}
+			err := b.Verify(ctx, "app/1.0", tt.device)
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrDeviceBinding)) {
+				t.Errorf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package ijwt
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"github.com/asynccnu/bff/config"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// cookie 模式相关的请求头和 cookie
+const (
+	HeaderAuthMode  = "X-Auth-Mode"  // cookie 或 header,不带时按客户端类型决定
+	HeaderCSRFToken = "X-CSRF-Token" // 双重提交,值和 CSRFCookie 相同
+
+	AccessCookie  = "ccnubox_access"
+	RefreshCookie = "ccnubox_refresh"
+	CSRFCookie    = "ccnubox_csrf" // 不是 HttpOnly,前端需要读出来放进请求头
+)
+
+const (
+	AuthModeCookie = "cookie"
+	AuthModeHeader = "header"
+)
+
+// ErrCSRF 用 cookie 认证的请求没有带上正确的 X-CSRF-Token
+var ErrCSRF = errors.New("CSRF 校验失败")
+
+// CookieAuth 按客户端选择把 token 放在响应头还是 cookie 里
+type CookieAuth struct {
+	clientTypes map[string]bool
+	domain      string
+	sameSite    http.SameSite
+	refreshPath string
+}
+
+func NewCookieAuth(cfg config.CookieConfig) *CookieAuth {
+	c := &CookieAuth{
+		clientTypes: make(map[string]bool, len(cfg.ClientTypes)),
+		domain:      cfg.Domain,
+		refreshPath: cfg.RefreshPath,
+	}
+	for _, t := range cfg.ClientTypes {
+		c.clientTypes[strings.ToLower(t)] = true
+	}
+	switch cfg.SameSite {
+	case "lax":
+		c.sameSite = http.SameSiteLaxMode
+	case "none":
+		c.sameSite = http.SameSiteNoneMode
+	default:
+		c.sameSite = http.SameSiteStrictMode
+	}
+	return c
+}
+
+// enabled 这次请求是否使用 cookie 模式
+func (c *CookieAuth) enabled(ctx *gin.Context, clientType string) bool {
+	switch strings.ToLower(ctx.GetHeader(HeaderAuthMode)) {
+	case AuthModeCookie:
+		return true
+	case AuthModeHeader:
+		return false
+	}
+	return c.clientTypes[clientType]
+}
+
+func (c *CookieAuth) set(ctx *gin.Context, name string, value string, path string, expiresAt time.Time, httpOnly bool) {
+	http.SetCookie(ctx.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   c.domain,
+		Expires:  expiresAt,
+		MaxAge:   int(time.Until(expiresAt).Seconds()),
+		Secure:   true,
+		HttpOnly: httpOnly,
+		SameSite: c.sameSite,
+	})
+}
+
+func (c *CookieAuth) setAccess(ctx *gin.Context, token string, expiresAt time.Time) {
+	c.set(ctx, AccessCookie, token, "/", expiresAt, true)
+}
+
+// setRefresh csrf token 和刷新令牌同时过期,刷新的时候沿用原来的值,
+// 不然刷新期间同时发出的请求带的还是旧值,会被误判
+func (c *CookieAuth) setRefresh(ctx *gin.Context, token string, expiresAt time.Time) {
+	c.set(ctx, RefreshCookie, token, c.refreshPath, expiresAt, true)
+	csrf, err := ctx.Cookie(CSRFCookie)
+	if err != nil || csrf == "" {
+		b := make([]byte, 32)
+		_, _ = rand.Read(b)
+		csrf = base64.RawURLEncoding.EncodeToString(b)
+	}
+	c.set(ctx, CSRFCookie, csrf, "/", expiresAt, false)
+}
+
+func (c *CookieAuth) clear(ctx *gin.Context) {
+	expired := time.Unix(0, 0)
+	c.set(ctx, AccessCookie, "", "/", expired, true)
+	c.set(ctx, RefreshCookie, "", c.refreshPath, expired, true)
+	c.set(ctx, CSRFCookie, "", "/", expired, false)
+}
+
+// usesCookie 请求是不是靠 cookie 认证的,带了 Authorization 的请求浏览器不会自动发出,不需要防 CSRF
+func usesCookie(ctx *gin.Context) bool {
+	if ctx.GetHeader("Authorization") != "" {
+		return false
+	}
+	for _, name := range []string{AccessCookie, RefreshCookie} {
+		if v, err := ctx.Cookie(name); err == nil && v != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// checkCSRF 跨站的页面读不到 csrf cookie,也就没法在请求头里带上同样的值
+func checkCSRF(ctx *gin.Context) error {
+	cookie, err := ctx.Cookie(CSRFCookie)
+	if err != nil || cookie == "" {
+		return ErrCSRF
+	}
+	if subtle.ConstantTimeCompare([]byte(cookie), []byte(ctx.GetHeader(HeaderCSRFToken))) != 1 {
+		return ErrCSRF
+	}
+	return nil
+}
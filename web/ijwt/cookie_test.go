@@ -0,0 +1,90 @@
+package ijwt
+
+import (
+	"errors"
+	"github.com/asynccnu/bff/config"
+	"github.com/asynccnu/bff/pkg/jwtx"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCookieMode(t *testing.T) {
+	keys := jwtx.NewHMACKeySet([]byte("0123456789abcdef0123456789abcdef"))
+	hdl := NewRedisJWTHandler(nil, nil, nil, keys, keys, 0,
+		NewDeviceBinding(config.DeviceBindingConfig{DefaultClientType: "app"}),
+		NewCookieAuth(config.CookieConfig{ClientTypes: []string{"web"}, SameSite: "strict", RefreshPath: "/api/v1/users/refresh_token"}))
+
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		wantCookie bool
+	}{
+		{name: "App uses headers"},
+		{name: "Configured client type", headers: map[string]string{HeaderClientType: "web"}, wantCookie: true},
+		{name: "Header overrides config", headers: map[string]string{HeaderClientType: "web", HeaderAuthMode: AuthModeHeader}},
+		{name: "Header opts in", headers: map[string]string{HeaderAuthMode: AuthModeCookie}, wantCookie: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Request = httptest.NewRequest("GET", "/api/v1/users/refresh_token", nil)
+			for k, v := range tt.headers {
+				ctx.Request.Header.Set(k, v)
+			}
+			if err := hdl.SetJWTToken(ctx, ClaimParams{StudentId: "2023000000", Ssid: "ssid"}); err != nil {
+				t.Fatal(err)
+			}
+			var access *http.Cookie
+			for _, c := range w.Result().Cookies() {
+				if c.Name == AccessCookie {
+					access = c
+				}
+			}
+			if (access != nil) != tt.wantCookie || (w.Header().Get("x-jwt-token") != "") == tt.wantCookie {
+				t.Fatalf("cookie = %v, header = %q, want cookie %v", access, w.Header().Get("x-jwt-token"), tt.wantCookie)
+			}
+			if access != nil && (!access.HttpOnly || !access.Secure || access.SameSite != http.SameSiteStrictMode) {
+				t.Errorf("access cookie = %+v, want HttpOnly, Secure and SameSite=Strict", access)
+			}
+		})
+	}
+}
+
+func TestCheckCSRF(t *testing.T) {
+	hdl := &RedisJWTHandler{}
+	tests := []struct {
+		name    string
+		auth    string
+		cookies map[string]string
+		header  string
+		wantErr bool
+	}{
+		{name: "Authorization header", auth: "Bearer token", cookies: map[string]string{AccessCookie: "token"}},
+		{name: "Guest without cookies"},
+		{name: "Cookie with matching token", cookies: map[string]string{AccessCookie: "token", CSRFCookie: "csrf"}, header: "csrf"},
+		{name: "Cookie without token", cookies: map[string]string{AccessCookie: "token", CSRFCookie: "csrf"}, wantErr: true},
+		{name: "Cookie with wrong token", cookies: map[string]string{RefreshCookie: "token", CSRFCookie: "csrf"}, header: "other", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest("POST", "/api/v1/users/logout", nil)
+			if tt.auth != "" {
+				ctx.Request.Header.Set("Authorization", tt.auth)
+			}
+			if tt.header != "" {
+				ctx.Request.Header.Set(HeaderCSRFToken, tt.header)
+			}
+			for k, v := range tt.cookies {
+				ctx.Request.AddCookie(&http.Cookie{Name: k, Value: v})
+			}
+			err := hdl.CheckCSRF(ctx)
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrCSRF)) {
+				t.Errorf("CheckCSRF() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
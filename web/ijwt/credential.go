@@ -0,0 +1,88 @@
+package ijwt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/asynccnu/bff/pkg/cryptox"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+var ErrCredentialNotFound = errors.New("登录凭证不存在或已过期")
+
+// Credential 调用下游时需要的学号和密码
+type Credential struct {
+	StudentId string `json:"student_id"`
+	Password  string `json:"password"`
+}
+
+// CredentialStore 按 ssid 在服务端保存登录凭证,token 里只带学号和 ssid
+type CredentialStore interface {
+	Save(ctx context.Context, ssid string, cred Credential, expiration time.Duration) error
+	// SaveIfAbsent 只在不存在时保存,用于把旧 token 里的密码迁移过来
+	SaveIfAbsent(ctx context.Context, ssid string, cred Credential, expiration time.Duration) error
+	// Get 不存在时返回 ErrCredentialNotFound
+	Get(ctx context.Context, ssid string) (Credential, error)
+	Delete(ctx context.Context, ssid string) error
+}
+
+// RedisCredentialStore 凭证加密后存在 redis 里,ssid 作为附加数据参与认证,密文被挪到别的 ssid 下也解不开
+type RedisCredentialStore struct {
+	cmd     redis.Cmdable
+	keyring *cryptox.Keyring
+}
+
+func NewRedisCredentialStore(cmd redis.Cmdable, keyring *cryptox.Keyring) CredentialStore {
+	return &RedisCredentialStore{cmd: cmd, keyring: keyring}
+}
+
+func (s *RedisCredentialStore) key(ssid string) string {
+	return fmt.Sprintf("ccnubox:users:credential:%s", ssid)
+}
+
+func (s *RedisCredentialStore) encrypt(ssid string, cred Credential) (string, error) {
+	data, err := json.Marshal(cred)
+	if err != nil {
+		return "", err
+	}
+	return s.keyring.Encrypt(data, []byte(ssid))
+}
+
+func (s *RedisCredentialStore) Save(ctx context.Context, ssid string, cred Credential, expiration time.Duration) error {
+	val, err := s.encrypt(ssid, cred)
+	if err != nil {
+		return err
+	}
+	return s.cmd.Set(ctx, s.key(ssid), val, expiration).Err()
+}
+
+func (s *RedisCredentialStore) SaveIfAbsent(ctx context.Context, ssid string, cred Credential, expiration time.Duration) error {
+	val, err := s.encrypt(ssid, cred)
+	if err != nil {
+		return err
+	}
+	return s.cmd.SetNX(ctx, s.key(ssid), val, expiration).Err()
+}
+
+func (s *RedisCredentialStore) Get(ctx context.Context, ssid string) (Credential, error) {
+	val, err := s.cmd.Get(ctx, s.key(ssid)).Result()
+	if errors.Is(err, redis.Nil) {
+		return Credential{}, ErrCredentialNotFound
+	}
+	if err != nil {
+		return Credential{}, err
+	}
+	data, err := s.keyring.Decrypt(val, []byte(ssid))
+	if err != nil {
+		return Credential{}, err
+	}
+	var cred Credential
+	err = json.Unmarshal(data, &cred)
+	return cred, err
+}
+
+func (s *RedisCredentialStore) Delete(ctx context.Context, ssid string) error {
+	return s.cmd.Del(ctx, s.key(ssid)).Err()
+}
@@ -0,0 +1,49 @@
+package ijwt
+
+import (
+	"errors"
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"time"
+)
+
+// ImpersonationTTL 模拟登录的 token 有效期,没有刷新令牌,过期之后需要重新发起
+const ImpersonationTTL = 15 * time.Minute
+
+var ErrImpersonation = errors.New("不能发起模拟登录")
+
+// Impersonate 管理员以学生的身份签发一个只读的短 token,会话记在学生的设备列表里。
+// token 绑定的是管理员自己的设备,不会写 cookie,免得把管理员自己的登录状态覆盖掉
+func (r *RedisJWTHandler) Impersonate(ctx *gin.Context, admin UserClaims, studentId string) (string, time.Time, error) {
+	if admin.IsService() || admin.IsImpersonated() || admin.StudentId == studentId {
+		return "", time.Time{}, ErrImpersonation
+	}
+	now := time.Now()
+	expiresAt := now.Add(ImpersonationTTL)
+	uc := UserClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+		StudentId:      studentId,
+		Ssid:           uuid.New().String(),
+		UserAgent:      admin.UserAgent,
+		Device:         admin.Device,
+		ImpersonatedBy: admin.StudentId,
+	}
+	err := r.addSession(ctx, studentId, Session{
+		Ssid:           uc.Ssid,
+		DeviceName:     ctx.GetHeader("X-Device-Name"),
+		UserAgent:      ctx.GetHeader("User-Agent"),
+		IP:             ctx.ClientIP(),
+		LoginTime:      now,
+		LastSeen:       now,
+		ExpiresAt:      expiresAt,
+		ImpersonatedBy: admin.StudentId,
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	tokenStr, err := r.accessKeys.Sign(uc, accessTokenType)
+	return tokenStr, expiresAt, err
+}
@@ -1,9 +1,9 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: ./model.go
+// Source: ./types.go
 //
 // Generated by this command:
 //
-//	mockgen -source=./model.go -package=ijwtmocks -destination=./mocks/ijwt.mock.go Handler
+//	mockgen -source=./types.go -package=ijwtmocks -destination=./mocks/ijwt.mock.go Handler
 //
 
 // Package ijwtmocks is a generated GoMock package.
@@ -11,8 +11,12 @@ package ijwtmocks
 
 import (
 	reflect "reflect"
+	time "time"
 
+	jwtx "github.com/asynccnu/bff/pkg/jwtx"
+	ijwt "github.com/asynccnu/bff/web/ijwt"
 	gin "github.com/gin-gonic/gin"
+	jwt "github.com/golang-jwt/jwt/v5"
 	gomock "go.uber.org/mock/gomock"
 )
 
@@ -39,6 +43,34 @@ func (m *MockHandler) EXPECT() *MockHandlerMockRecorder {
 	return m.recorder
 }
 
+// AccessKeyfunc mocks base method.
+func (m *MockHandler) AccessKeyfunc() jwt.Keyfunc {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AccessKeyfunc")
+	ret0, _ := ret[0].(jwt.Keyfunc)
+	return ret0
+}
+
+// AccessKeyfunc indicates an expected call of AccessKeyfunc.
+func (mr *MockHandlerMockRecorder) AccessKeyfunc() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AccessKeyfunc", reflect.TypeOf((*MockHandler)(nil).AccessKeyfunc))
+}
+
+// CheckCSRF mocks base method.
+func (m *MockHandler) CheckCSRF(ctx *gin.Context) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CheckCSRF", ctx)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CheckCSRF indicates an expected call of CheckCSRF.
+func (mr *MockHandlerMockRecorder) CheckCSRF(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CheckCSRF", reflect.TypeOf((*MockHandler)(nil).CheckCSRF), ctx)
+}
+
 // CheckSession mocks base method.
 func (m *MockHandler) CheckSession(ctx *gin.Context, ssid string) (bool, error) {
 	m.ctrl.T.Helper()
@@ -68,6 +100,35 @@ func (mr *MockHandlerMockRecorder) ClearToken(ctx any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClearToken", reflect.TypeOf((*MockHandler)(nil).ClearToken), ctx)
 }
 
+// Credential mocks base method.
+func (m *MockHandler) Credential(ctx *gin.Context, uc ijwt.UserClaims) (ijwt.Credential, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Credential", ctx, uc)
+	ret0, _ := ret[0].(ijwt.Credential)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Credential indicates an expected call of Credential.
+func (mr *MockHandlerMockRecorder) Credential(ctx, uc any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Credential", reflect.TypeOf((*MockHandler)(nil).Credential), ctx, uc)
+}
+
+// ExtractRefreshToken mocks base method.
+func (m *MockHandler) ExtractRefreshToken(ctx *gin.Context) string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ExtractRefreshToken", ctx)
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// ExtractRefreshToken indicates an expected call of ExtractRefreshToken.
+func (mr *MockHandlerMockRecorder) ExtractRefreshToken(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExtractRefreshToken", reflect.TypeOf((*MockHandler)(nil).ExtractRefreshToken), ctx)
+}
+
 // ExtractToken mocks base method.
 func (m *MockHandler) ExtractToken(ctx *gin.Context) string {
 	m.ctrl.T.Helper()
@@ -82,58 +143,173 @@ func (mr *MockHandlerMockRecorder) ExtractToken(ctx any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ExtractToken", reflect.TypeOf((*MockHandler)(nil).ExtractToken), ctx)
 }
 
-// JWTKey mocks base method.
-func (m *MockHandler) JWTKey() string {
+// Impersonate mocks base method.
+func (m *MockHandler) Impersonate(ctx *gin.Context, admin ijwt.UserClaims, studentId string) (string, time.Time, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "JWTKey")
+	ret := m.ctrl.Call(m, "Impersonate", ctx, admin, studentId)
 	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(time.Time)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Impersonate indicates an expected call of Impersonate.
+func (mr *MockHandlerMockRecorder) Impersonate(ctx, admin, studentId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Impersonate", reflect.TypeOf((*MockHandler)(nil).Impersonate), ctx, admin, studentId)
+}
+
+// JWKS mocks base method.
+func (m *MockHandler) JWKS() jwtx.JWKS {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "JWKS")
+	ret0, _ := ret[0].(jwtx.JWKS)
 	return ret0
 }
 
-// JWTKey indicates an expected call of JWTKey.
-func (mr *MockHandlerMockRecorder) JWTKey() *gomock.Call {
+// JWKS indicates an expected call of JWKS.
+func (mr *MockHandlerMockRecorder) JWKS() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "JWTKey", reflect.TypeOf((*MockHandler)(nil).JWTKey))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "JWKS", reflect.TypeOf((*MockHandler)(nil).JWKS))
 }
 
-// RCJWTKey mocks base method.
-func (m *MockHandler) RCJWTKey() string {
+// MigrateCredential mocks base method.
+func (m *MockHandler) MigrateCredential(ctx *gin.Context, ssid string, cred ijwt.Credential) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "RCJWTKey")
-	ret0, _ := ret[0].(string)
+	ret := m.ctrl.Call(m, "MigrateCredential", ctx, ssid, cred)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MigrateCredential indicates an expected call of MigrateCredential.
+func (mr *MockHandlerMockRecorder) MigrateCredential(ctx, ssid, cred any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MigrateCredential", reflect.TypeOf((*MockHandler)(nil).MigrateCredential), ctx, ssid, cred)
+}
+
+// RefreshKeyfunc mocks base method.
+func (m *MockHandler) RefreshKeyfunc() jwt.Keyfunc {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RefreshKeyfunc")
+	ret0, _ := ret[0].(jwt.Keyfunc)
+	return ret0
+}
+
+// RefreshKeyfunc indicates an expected call of RefreshKeyfunc.
+func (mr *MockHandlerMockRecorder) RefreshKeyfunc() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RefreshKeyfunc", reflect.TypeOf((*MockHandler)(nil).RefreshKeyfunc))
+}
+
+// RevokeOtherSessions mocks base method.
+func (m *MockHandler) RevokeOtherSessions(ctx *gin.Context, studentId, current string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeOtherSessions", ctx, studentId, current)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeOtherSessions indicates an expected call of RevokeOtherSessions.
+func (mr *MockHandlerMockRecorder) RevokeOtherSessions(ctx, studentId, current any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeOtherSessions", reflect.TypeOf((*MockHandler)(nil).RevokeOtherSessions), ctx, studentId, current)
+}
+
+// RevokeSession mocks base method.
+func (m *MockHandler) RevokeSession(ctx *gin.Context, studentId, ssid string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeSession", ctx, studentId, ssid)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeSession indicates an expected call of RevokeSession.
+func (mr *MockHandlerMockRecorder) RevokeSession(ctx, studentId, ssid any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeSession", reflect.TypeOf((*MockHandler)(nil).RevokeSession), ctx, studentId, ssid)
+}
+
+// RotateRefreshToken mocks base method.
+func (m *MockHandler) RotateRefreshToken(ctx *gin.Context, rc ijwt.RefreshClaims) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateRefreshToken", ctx, rc)
+	ret0, _ := ret[0].(error)
 	return ret0
 }
 
-// RCJWTKey indicates an expected call of RCJWTKey.
-func (mr *MockHandlerMockRecorder) RCJWTKey() *gomock.Call {
+// RotateRefreshToken indicates an expected call of RotateRefreshToken.
+func (mr *MockHandlerMockRecorder) RotateRefreshToken(ctx, rc any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RCJWTKey", reflect.TypeOf((*MockHandler)(nil).RCJWTKey))
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateRefreshToken", reflect.TypeOf((*MockHandler)(nil).RotateRefreshToken), ctx, rc)
+}
+
+// Sessions mocks base method.
+func (m *MockHandler) Sessions(ctx *gin.Context, studentId string) ([]ijwt.Session, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Sessions", ctx, studentId)
+	ret0, _ := ret[0].([]ijwt.Session)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Sessions indicates an expected call of Sessions.
+func (mr *MockHandlerMockRecorder) Sessions(ctx, studentId any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Sessions", reflect.TypeOf((*MockHandler)(nil).Sessions), ctx, studentId)
 }
 
 // SetJWTToken mocks base method.
-func (m *MockHandler) SetJWTToken(ctx *gin.Context, uid int64, ssid string) error {
+func (m *MockHandler) SetJWTToken(ctx *gin.Context, cp ijwt.ClaimParams) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetJWTToken", ctx, uid, ssid)
+	ret := m.ctrl.Call(m, "SetJWTToken", ctx, cp)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SetJWTToken indicates an expected call of SetJWTToken.
-func (mr *MockHandlerMockRecorder) SetJWTToken(ctx, uid, ssid any) *gomock.Call {
+func (mr *MockHandlerMockRecorder) SetJWTToken(ctx, cp any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetJWTToken", reflect.TypeOf((*MockHandler)(nil).SetJWTToken), ctx, uid, ssid)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetJWTToken", reflect.TypeOf((*MockHandler)(nil).SetJWTToken), ctx, cp)
 }
 
 // SetLoginToken mocks base method.
-func (m *MockHandler) SetLoginToken(ctx *gin.Context, uid int64) error {
+func (m *MockHandler) SetLoginToken(ctx *gin.Context, studentId, password string) error {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "SetLoginToken", ctx, uid)
+	ret := m.ctrl.Call(m, "SetLoginToken", ctx, studentId, password)
 	ret0, _ := ret[0].(error)
 	return ret0
 }
 
 // SetLoginToken indicates an expected call of SetLoginToken.
-func (mr *MockHandlerMockRecorder) SetLoginToken(ctx, uid any) *gomock.Call {
+func (mr *MockHandlerMockRecorder) SetLoginToken(ctx, studentId, password any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLoginToken", reflect.TypeOf((*MockHandler)(nil).SetLoginToken), ctx, studentId, password)
+}
+
+// Touch mocks base method.
+func (m *MockHandler) Touch(ctx *gin.Context, uc ijwt.UserClaims) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Touch", ctx, uc)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Touch indicates an expected call of Touch.
+func (mr *MockHandlerMockRecorder) Touch(ctx, uc any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Touch", reflect.TypeOf((*MockHandler)(nil).Touch), ctx, uc)
+}
+
+// VerifyDevice mocks base method.
+func (m *MockHandler) VerifyDevice(ctx *gin.Context, userAgent string, d ijwt.Device) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyDevice", ctx, userAgent, d)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyDevice indicates an expected call of VerifyDevice.
+func (mr *MockHandlerMockRecorder) VerifyDevice(ctx, userAgent, d any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetLoginToken", reflect.TypeOf((*MockHandler)(nil).SetLoginToken), ctx, uid)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyDevice", reflect.TypeOf((*MockHandler)(nil).VerifyDevice), ctx, userAgent, d)
 }
@@ -1,8 +1,11 @@
 package ijwt
 
 import (
+	"errors"
 	"fmt"
 	"github.com/asynccnu/bff/pkg/ginx"
+	"github.com/asynccnu/bff/pkg/jwtx"
+	"github.com/asynccnu/bff/pkg/logger"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
@@ -13,21 +16,37 @@ import (
 
 // RedisJWTHandler 实现了处理 JWT 的接口，并使用 Redis 进行支持
 type RedisJWTHandler struct {
-	cmd           redis.Cmdable     // Redis 命令接口，用于与 Redis 进行交互
-	signingMethod jwt.SigningMethod // JWT 签名方法
-	rcExpiration  time.Duration     // 刷新令牌的过期时间，防止缓存过大
-	jwtKey        []byte            // 用于签署 JWT 的密钥
-	rcJWTKey      []byte            // 用于签署刷新令牌的密钥
+	cmd          redis.Cmdable   // Redis 命令接口，用于与 Redis 进行交互
+	rcExpiration time.Duration   // 刷新令牌的过期时间，防止缓存过大
+	accessKeys   *jwtx.KeySet    // 用于签署 JWT 的密钥
+	refreshKeys  *jwtx.KeySet    // 用于签署刷新令牌的密钥
+	credentials  CredentialStore // 服务端保存的学号密码
+	maxDevices   int             // 每个学号同时在线的设备数上限,0 表示不限制
+	binding      *DeviceBinding  // token 和设备的绑定方式
+	touched      *touchThrottle  // 最后活跃时间的写入限流
+	cookies      *CookieAuth     // 网页端的 cookie 模式
+	l            logger.Logger
 }
 
-// JWTKey 返回用于签署 JWT 的密钥
-func (r *RedisJWTHandler) JWTKey() []byte {
-	return r.jwtKey
+// 两种 token 用 header 里的 typ 区分,非对称签名时它们共用一组密钥,只能靠这个区分
+const (
+	accessTokenType  = "at+jwt"
+	refreshTokenType = "rt+jwt"
+)
+
+// AccessKeyfunc 验证 JWT 时使用
+func (r *RedisJWTHandler) AccessKeyfunc() jwt.Keyfunc {
+	return r.accessKeys.Keyfunc(accessTokenType)
+}
+
+// RefreshKeyfunc 验证刷新令牌时使用
+func (r *RedisJWTHandler) RefreshKeyfunc() jwt.Keyfunc {
+	return r.refreshKeys.Keyfunc(refreshTokenType)
 }
 
-// RCJWTKey 返回用于签署刷新令牌的密钥
-func (r *RedisJWTHandler) RCJWTKey() []byte {
-	return r.rcJWTKey
+// JWKS 返回可以公开的验签公钥
+func (r *RedisJWTHandler) JWKS() jwtx.JWKS {
+	return r.accessKeys.JWKS()
 }
 
 // ClearToken 清除客户端的 JWT 和刷新令牌，并在 Redis 中记录已过期的会话
@@ -35,20 +54,45 @@ func (r *RedisJWTHandler) ClearToken(ctx *gin.Context) error {
 	// 要求客户端设置为空
 	ctx.Header("x-jwt-token", "")
 	ctx.Header("x-refresh-token", "")
+	if usesCookie(ctx) || r.cookieMode(ctx) {
+		r.cookies.clear(ctx)
+	}
 	// 在 Redis 中记录已过期的会话 TODO 这里需要解耦合,但是写的太抽象了一时半会儿看不明白,先这么做
 	uc, err := ginx.GetClaims[UserClaims](ctx)
 	if err != nil {
 		return err
 	}
 
-	return r.cmd.Set(ctx, fmt.Sprintf("ccnubox:users:ssid:%s", uc.Ssid), "", r.rcExpiration).Err()
+	return r.revoke(ctx, uc.StudentId, uc.Ssid)
+}
+
+// revoke 吊销会话,同时删掉服务端保存的凭证、刷新令牌家族和会话列表中的记录
+func (r *RedisJWTHandler) revoke(ctx *gin.Context, studentId string, ssid string) error {
+	return errors.Join(
+		r.cmd.Set(ctx, fmt.Sprintf("ccnubox:users:ssid:%s", ssid), "", r.rcExpiration).Err(),
+		r.credentials.Delete(ctx, ssid),
+		r.cmd.Del(ctx, r.familyKey(ssid)).Err(),
+		r.cmd.HDel(ctx, r.sessionsKey(studentId), ssid).Err(),
+		r.cmd.HDel(ctx, r.lastSeenKey(studentId), ssid).Err(),
+		r.cmd.HDel(ctx, r.userAgentKey(studentId), ssid).Err(),
+	)
 }
 
-// ExtractToken 从请求中提取并返回 JWT
+// ExtractToken 从请求中提取并返回 JWT,没有 Authorization 时从 cookie 里取
 func (r *RedisJWTHandler) ExtractToken(ctx *gin.Context) string {
+	return extractToken(ctx, AccessCookie)
+}
+
+// ExtractRefreshToken 从请求中提取并返回刷新令牌
+func (r *RedisJWTHandler) ExtractRefreshToken(ctx *gin.Context) string {
+	return extractToken(ctx, RefreshCookie)
+}
+
+func extractToken(ctx *gin.Context, cookie string) string {
 	authCode := ctx.GetHeader("Authorization")
 	if authCode == "" {
-		return ""
+		token, _ := ctx.Cookie(cookie)
+		return token
 	}
 	segs := strings.Split(authCode, " ")
 	if len(segs) != 2 {
@@ -57,61 +101,140 @@ func (r *RedisJWTHandler) ExtractToken(ctx *gin.Context) string {
 	return segs[1]
 }
 
-// SetLoginToken 设置用户的刷新令牌和 JWT
+// CheckCSRF 靠 cookie 认证的请求必须带上和 csrf cookie 相同的 X-CSRF-Token,用 Authorization 的请求直接通过
+func (r *RedisJWTHandler) CheckCSRF(ctx *gin.Context) error {
+	if !usesCookie(ctx) {
+		return nil
+	}
+	return checkCSRF(ctx)
+}
+
+// cookieMode 这次请求签发的 token 是否写进 cookie
+func (r *RedisJWTHandler) cookieMode(ctx *gin.Context) bool {
+	return r.cookies.enabled(ctx, r.binding.clientType(ctx))
+}
+
+// SetLoginToken 设置用户的刷新令牌和 JWT,密码只保存在服务端,和刷新令牌同时过期
 func (r *RedisJWTHandler) SetLoginToken(ctx *gin.Context, studentId string, password string) error {
+	device, err := r.binding.Bind(ctx)
+	if err != nil {
+		return err
+	}
 	cp := ClaimParams{
 		StudentId: studentId,
-		Password:  password,
 		Ssid:      uuid.New().String(),
 		UserAgent: ctx.GetHeader("User-Agent"),
+		Device:    device,
+	}
+	err = r.credentials.Save(ctx, cp.Ssid, Credential{StudentId: studentId, Password: password}, r.rcExpiration)
+	if err != nil {
+		return err
+	}
+	// 登录时建立刷新令牌家族
+	jti := uuid.New().String()
+	err = r.cmd.Set(ctx, r.familyKey(cp.Ssid), jti, r.rcExpiration).Err()
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	err = r.setRefreshToken(ctx, cp, jti, now.Add(r.rcExpiration))
+	if err != nil {
+		return err
 	}
-	err := r.setRefreshToken(ctx, cp)
+	err = r.addSession(ctx, studentId, Session{
+		Ssid:       cp.Ssid,
+		DeviceName: ctx.GetHeader("X-Device-Name"),
+		UserAgent:  cp.UserAgent,
+		IP:         ctx.ClientIP(),
+		LoginTime:  now,
+		LastSeen:   now,
+		ExpiresAt:  now.Add(r.rcExpiration),
+	})
 	if err != nil {
 		return err
 	}
 	return r.SetJWTToken(ctx, cp)
 }
 
-// setRefreshToken 生成并设置用户的刷新令牌
-func (r *RedisJWTHandler) setRefreshToken(ctx *gin.Context, cp ClaimParams) error {
+// setRefreshToken 生成并设置用户的刷新令牌,jti 用来识别它是家族中的哪一个
+func (r *RedisJWTHandler) setRefreshToken(ctx *gin.Context, cp ClaimParams, jti string, expiresAt time.Time) error {
 	rc := RefreshClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(r.rcExpiration)),
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 		},
 		StudentId: cp.StudentId,
-		Password:  cp.Password,
 		Ssid:      cp.Ssid,
 		UserAgent: cp.UserAgent,
+		Device:    cp.Device,
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, rc)
-	tokenStr, err := token.SignedString(r.RCJWTKey())
+	tokenStr, err := r.refreshKeys.Sign(rc, refreshTokenType)
 	if err != nil {
 		return err
 	}
+	if r.cookieMode(ctx) {
+		r.cookies.setRefresh(ctx, tokenStr, expiresAt)
+		return nil
+	}
 	ctx.Header("x-refresh-token", tokenStr)
 	return nil
 }
 
 // SetJWTToken 生成并设置用户的 JWT
 func (r *RedisJWTHandler) SetJWTToken(ctx *gin.Context, cp ClaimParams) error {
+	expiresAt := time.Now().Add(time.Hour * 1)
 	uc := UserClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour * 1)), //一天过期一次
+			ExpiresAt: jwt.NewNumericDate(expiresAt), //一天过期一次
 		},
 		StudentId: cp.StudentId,
-		Password:  cp.Password,
 		Ssid:      cp.Ssid,
 		UserAgent: cp.UserAgent,
+		Device:    cp.Device,
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, uc)
-	tokenStr, err := token.SignedString(r.JWTKey())
+	tokenStr, err := r.accessKeys.Sign(uc, accessTokenType)
 	if err != nil {
 		return err
 	}
+	if r.cookieMode(ctx) {
+		r.cookies.setAccess(ctx, tokenStr, expiresAt)
+		return nil
+	}
 	ctx.Header("x-jwt-token", tokenStr)
 	return nil
 }
 
+// Credential 获取当前会话保存在服务端的学号密码,给需要密码的下游调用使用
+func (r *RedisJWTHandler) Credential(ctx *gin.Context, uc UserClaims) (Credential, error) {
+	cred, err := r.credentials.Get(ctx, uc.Ssid)
+	if errors.Is(err, ErrCredentialNotFound) && uc.Password != "" {
+		// 旧 token 里还带着密码,顺手迁移到服务端
+		cred = Credential{StudentId: uc.StudentId, Password: uc.Password}
+		return cred, r.MigrateCredential(ctx, uc.Ssid, cred)
+	}
+	if err != nil {
+		return Credential{}, err
+	}
+	if cred.StudentId != uc.StudentId {
+		return Credential{}, errors.New("登录凭证与当前用户不匹配")
+	}
+	return cred, nil
+}
+
+// MigrateCredential 把旧版本 token 里携带的密码存到服务端,已经存在时不会覆盖
+// 旧 token 全部过期之后(刷新令牌有效期 7 天),这个方法和 claims 里的 Password 字段就可以删掉了
+func (r *RedisJWTHandler) MigrateCredential(ctx *gin.Context, ssid string, cred Credential) error {
+	if cred.Password == "" {
+		return nil
+	}
+	return r.credentials.SaveIfAbsent(ctx, ssid, cred, r.rcExpiration)
+}
+
+// VerifyDevice 检查请求是否来自 token 绑定的设备
+func (r *RedisJWTHandler) VerifyDevice(ctx *gin.Context, userAgent string, d Device) error {
+	return r.binding.Verify(ctx, userAgent, d)
+}
+
 // CheckSession 检查给定 ssid 的会话是否有效
 func (r *RedisJWTHandler) CheckSession(ctx *gin.Context, ssid string) (bool, error) {
 	val, err := r.cmd.Exists(ctx, fmt.Sprintf("ccnubox:users:ssid:%s", ssid)).Result()
@@ -119,13 +242,19 @@ func (r *RedisJWTHandler) CheckSession(ctx *gin.Context, ssid string) (bool, err
 }
 
 // NewRedisJWTHandler 创建并返回一个新的 RedisJWTHandler 实例
-func NewRedisJWTHandler(cmd redis.Cmdable, jwtKey string, rcJWTKey string) Handler {
+// accessKeys 和 refreshKeys 决定了签名算法,见 config.JWTConfig.KeySets
+func NewRedisJWTHandler(cmd redis.Cmdable, credentials CredentialStore, l logger.Logger, accessKeys *jwtx.KeySet, refreshKeys *jwtx.KeySet, maxDevices int, binding *DeviceBinding, cookies *CookieAuth) Handler {
 	return &RedisJWTHandler{
-		cmd:           cmd,                    //redis实体
-		signingMethod: jwt.SigningMethodHS256, //签名的加密方式
-		rcExpiration:  time.Hour * 24 * 7,     //设置为一周之后过期
-		jwtKey:        []byte(jwtKey),
-		rcJWTKey:      []byte(rcJWTKey),
+		cmd:          cmd,                //redis实体
+		rcExpiration: time.Hour * 24 * 7, //设置为一周之后过期
+		accessKeys:   accessKeys,
+		refreshKeys:  refreshKeys,
+		credentials:  credentials,
+		maxDevices:   maxDevices,
+		binding:      binding,
+		touched:      newTouchThrottle(),
+		cookies:      cookies,
+		l:            l,
 	}
 }
 
@@ -133,16 +262,38 @@ func NewRedisJWTHandler(cmd redis.Cmdable, jwtKey string, rcJWTKey string) Handl
 type UserClaims struct {
 	jwt.RegisteredClaims
 	StudentId string // 学生 ID
-	Password  string // 密码（仅用于演示，实际应用中不会存储密码）
+	// Deprecated: 密码已经改为保存在服务端,见 Credential,这里只用来兼容旧 token,新签发的 token 不再包含
+	Password  string `json:",omitempty"`
 	Ssid      string // 会话 ID
-	UserAgent string // 用户代理信息
+	UserAgent string // 登录时的用户代理信息
+	Device           // 绑定的设备
+
+	// 管理员模拟登录时是管理员的学号,这时 StudentId 是被模拟的学生,只能访问只读接口
+	ImpersonatedBy string `json:",omitempty"`
+
+	// 通过 API Key 认证的调用方(内部任务、运维脚本)没有 token,由 API Key 映射过来,
+	// 这时 StudentId 是 "apikey:<id>",权限只看 Scopes,这两个字段不会出现在 token 里
+	ApiKeyId string   `json:"-"`
+	Scopes   []string `json:"-"`
+}
+
+// IsService 是否是通过 API Key 认证的调用方
+func (uc UserClaims) IsService() bool {
+	return uc.ApiKeyId != ""
+}
+
+// IsImpersonated 是否是管理员模拟登录签发的 token
+func (uc UserClaims) IsImpersonated() bool {
+	return uc.ImpersonatedBy != ""
 }
 
 // RefreshClaims 定义了刷新令牌中的声明
 type RefreshClaims struct {
 	jwt.RegisteredClaims
 	StudentId string // 学生 ID
-	Password  string // 密码
+	// Deprecated: 同 UserClaims.Password
+	Password  string `json:",omitempty"`
 	Ssid      string // 会话 ID
-	UserAgent string // 用户代理信息
+	UserAgent string // 登录时的用户代理信息
+	Device           // 绑定的设备
 }
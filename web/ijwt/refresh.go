@@ -0,0 +1,104 @@
+package ijwt
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"github.com/asynccnu/bff/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"time"
+)
+
+//go:embed refresh_rotate.lua
+var rotateScript string
+
+var (
+	// ErrRefreshTokenReused 出示了已经被轮换掉的刷新令牌,说明令牌很可能已经泄露,整个会话会被吊销
+	ErrRefreshTokenReused = errors.New("刷新令牌被重复使用")
+	// ErrRefreshTokenInvalid 刷新令牌所属的会话已经不存在了
+	ErrRefreshTokenInvalid = errors.New("刷新令牌已失效")
+)
+
+// refreshGracePeriod 刷新令牌被轮换掉之后还能再用多久,在这之内重复出示不算泄露
+const refreshGracePeriod = 10 * time.Second
+
+func (r *RedisJWTHandler) familyKey(ssid string) string {
+	return fmt.Sprintf("ccnubox:users:refresh:%s", ssid)
+}
+
+func (r *RedisJWTHandler) prevKey(ssid string) string {
+	return fmt.Sprintf("ccnubox:users:refresh_prev:%s", ssid)
+}
+
+// rotate 把家族中当前有效的 jti 换成 next,返回这次应该签发的刷新令牌的 jti
+// 宽限期内重复出示刚被换掉的 jti 时不会再换,返回的是已经换上去的那个,并发刷新的两个请求拿到的是同一个刷新令牌
+func (r *RedisJWTHandler) rotate(ctx context.Context, ssid string, jti string, next string, ttl time.Duration) (string, error) {
+	res, err := r.cmd.Eval(ctx, rotateScript, []string{r.familyKey(ssid), r.prevKey(ssid)},
+		jti, next, ttl.Milliseconds(), refreshGracePeriod.Milliseconds()).Slice()
+	if err != nil {
+		return "", err
+	}
+	if len(res) != 2 {
+		return "", fmt.Errorf("刷新令牌轮换脚本返回了 %d 个值", len(res))
+	}
+	code, _ := res[0].(int64)
+	issued, _ := res[1].(string)
+	switch code {
+	case -1:
+		return "", ErrRefreshTokenInvalid
+	case 0:
+		return "", ErrRefreshTokenReused
+	}
+	return issued, nil
+}
+
+// RotateRefreshToken 用刷新令牌换一对新的令牌,旧的刷新令牌在 refreshGracePeriod 之后失效
+// 同一个会话的刷新令牌构成一个家族,redis 里只记录家族中最新的那个,新令牌的过期时间沿用旧令牌,会话不会因为刷新而无限延长
+func (r *RedisJWTHandler) RotateRefreshToken(ctx *gin.Context, rc RefreshClaims) error {
+	expiresAt := time.Now().Add(r.rcExpiration)
+	if rc.ExpiresAt != nil {
+		expiresAt = rc.ExpiresAt.Time
+	}
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return ErrRefreshTokenInvalid
+	}
+
+	// 刷新令牌被偷走之后也不能在别的设备上使用
+	if err := r.binding.Verify(ctx, rc.UserAgent, rc.Device); err != nil {
+		return err
+	}
+
+	next, err := r.rotate(ctx, rc.Ssid, rc.ID, uuid.New().String(), ttl)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrRefreshTokenReused):
+		r.l.Warn("安全事件:检测到刷新令牌被重复使用,已吊销整个会话",
+			logger.String("student_id", rc.StudentId),
+			logger.String("ssid", rc.Ssid),
+			logger.String("jti", rc.ID),
+			logger.String("ip", ctx.ClientIP()),
+			logger.String("user_agent", ctx.GetHeader("User-Agent")),
+		)
+		return errors.Join(err, r.revoke(ctx, rc.StudentId, rc.Ssid))
+	default:
+		return err
+	}
+
+	cp := ClaimParams{
+		StudentId: rc.StudentId,
+		Ssid:      rc.Ssid,
+		UserAgent: rc.UserAgent,
+		Device:    rc.Device,
+	}
+	// 绑定了设备的会话,User-Agent 只是记录用的,换成最新的
+	if rc.DeviceId != "" {
+		cp.UserAgent = ctx.GetHeader("User-Agent")
+	}
+	if err = r.setRefreshToken(ctx, cp, next, expiresAt); err != nil {
+		return err
+	}
+	return r.SetJWTToken(ctx, cp)
+}
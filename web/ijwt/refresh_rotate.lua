@@ -0,0 +1,33 @@
+-- 刷新令牌轮换,检查和替换必须是原子的,否则并发的两次刷新都能通过检查
+-- 刷新令牌家族,值是当前唯一有效的刷新令牌的 jti
+local key = KEYS[1]
+-- 刚被轮换掉的 jti,只保留宽限期那么久
+local prevKey = KEYS[2]
+-- 本次出示的刷新令牌的 jti,旧版本签发的令牌没有 jti,是空字符串
+local jti = ARGV[1]
+-- 新签发的刷新令牌的 jti
+local nextJti = ARGV[2]
+-- 家族的剩余有效期(毫秒),和刷新令牌的过期时间对齐
+local ttl = tonumber(ARGV[3])
+-- 宽限期(毫秒)
+local grace = tonumber(ARGV[4])
+
+local current = redis.call('GET', key)
+if current == false then
+    if jti ~= '' then
+        -- 家族不存在,可能已经被吊销或者过期了
+        return {-1, ''}
+    end
+    -- 旧版本的令牌第一次刷新,从这里开始建立家族
+elseif current ~= jti then
+    if redis.call('GET', prevKey) == jti then
+        -- 宽限期内又出示了刚被轮换掉的令牌,多半是两个标签页同时刷新,或者响应丢了客户端在重试,
+        -- 不再轮换,直接给它当前有效的那个
+        return {2, current}
+    end
+    -- 已经被轮换掉的令牌又被拿出来用了
+    return {0, ''}
+end
+redis.call('SET', key, nextJti, 'PX', ttl)
+redis.call('SET', prevKey, jti, 'PX', grace)
+return {1, nextJti}
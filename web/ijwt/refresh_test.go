@@ -0,0 +1,66 @@
+package ijwt
+
+import (
+	"context"
+	"errors"
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRotateConcurrently(t *testing.T) {
+	mr := miniredis.RunT(t)
+	r := &RedisJWTHandler{cmd: redis.NewClient(&redis.Options{Addr: mr.Addr()})}
+	ctx := context.Background()
+	if err := r.cmd.Set(ctx, r.familyKey("ssid"), "jti-1", time.Hour).Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 两个标签页同时拿同一个刷新令牌来刷新,都应该成功,并且拿到同一个新令牌
+	const n = 8
+	var wg sync.WaitGroup
+	issued := make([]string, n)
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			issued[i], errs[i] = r.rotate(ctx, "ssid", "jti-1", "jti-2-"+strconv.Itoa(i), time.Hour)
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < n; i++ {
+		if errs[i] != nil || issued[i] != issued[0] {
+			t.Fatalf("rotate() #%d = %q, %v, want %q", i, issued[i], errs[i], issued[0])
+		}
+	}
+
+	steps := []struct {
+		name    string
+		wait    time.Duration
+		jti     string
+		want    string
+		wantErr error
+	}{
+		{name: "Rotated token keeps rotating", jti: issued[0], want: "jti-3"},
+		{name: "Previous token within grace period", jti: issued[0], want: "jti-3"},
+		{name: "Older token reused", jti: "jti-1", wantErr: ErrRefreshTokenReused},
+		{name: "Previous token after grace period", wait: refreshGracePeriod + time.Second, jti: issued[0], wantErr: ErrRefreshTokenReused},
+	}
+	for _, s := range steps {
+		mr.FastForward(s.wait)
+		got, err := r.rotate(ctx, "ssid", s.jti, "jti-3", time.Hour)
+		if !errors.Is(err, s.wantErr) || got != s.want {
+			t.Errorf("%s: rotate() = %q, %v, want %q, %v", s.name, got, err, s.want, s.wantErr)
+		}
+	}
+
+	// 会话被吊销之后家族就没有了
+	mr.Del(r.familyKey("ssid"))
+	if _, err := r.rotate(ctx, "ssid", "jti-3", "jti-4", time.Hour); !errors.Is(err, ErrRefreshTokenInvalid) {
+		t.Errorf("rotate() after revoke error = %v, want %v", err, ErrRefreshTokenInvalid)
+	}
+}
@@ -0,0 +1,172 @@
+package ijwt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/asynccnu/bff/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var ErrSessionNotFound = errors.New("会话不存在")
+
+// Session 一个学号下的一次登录,也就是一台设备
+type Session struct {
+	Ssid       string    `json:"ssid"`
+	DeviceName string    `json:"device_name"` // 客户端登录时通过 X-Device-Name 请求头上报,没有就是空
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	LoginTime  time.Time `json:"login_time"`
+	LastSeen   time.Time `json:"last_seen"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	// 管理员模拟登录的会话,学生在设备列表里能看到是谁在什么时候看过
+	ImpersonatedBy string `json:"impersonated_by,omitempty"`
+}
+
+// 每个学号一个 hash 记录所有活跃会话,最后活跃时间每个请求都会更新,单独放一个 hash 避免反复序列化整个会话
+func (r *RedisJWTHandler) sessionsKey(studentId string) string {
+	return fmt.Sprintf("ccnubox:users:sessions:%s", studentId)
+}
+
+func (r *RedisJWTHandler) lastSeenKey(studentId string) string {
+	return fmt.Sprintf("ccnubox:users:sessions_seen:%s", studentId)
+}
+
+// 绑定设备之后 User-Agent 变化不再导致认证失败,最新的 User-Agent 记在这里
+func (r *RedisJWTHandler) userAgentKey(studentId string) string {
+	return fmt.Sprintf("ccnubox:users:sessions_ua:%s", studentId)
+}
+
+// addSession 登录时记录会话,超过设备数上限时把最久没有活跃的会话踢掉
+func (r *RedisJWTHandler) addSession(ctx *gin.Context, studentId string, s Session) error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	pipe := r.cmd.TxPipeline()
+	pipe.HSet(ctx, r.sessionsKey(studentId), s.Ssid, data)
+	pipe.HSet(ctx, r.lastSeenKey(studentId), s.Ssid, s.LastSeen.UnixMilli())
+	// 最新的会话比之前的都晚过期,整个索引跟着它续期就行
+	pipe.Expire(ctx, r.sessionsKey(studentId), r.rcExpiration)
+	pipe.Expire(ctx, r.lastSeenKey(studentId), r.rcExpiration)
+	if _, err = pipe.Exec(ctx); err != nil {
+		return err
+	}
+
+	// 模拟登录的会话不算设备数,也不能因为管理员看了一眼把学生自己的设备踢掉
+	if r.maxDevices <= 0 || s.ImpersonatedBy != "" {
+		return nil
+	}
+	sessions, err := r.Sessions(ctx, studentId)
+	if err != nil {
+		return err
+	}
+	sessions = slices.DeleteFunc(sessions, func(other Session) bool { return other.ImpersonatedBy != "" })
+	var errs []error
+	// Sessions 按最后活跃时间倒序,排在上限之后的就是要踢掉的
+	for i := r.maxDevices; i < len(sessions); i++ {
+		if sessions[i].Ssid == s.Ssid {
+			continue
+		}
+		r.l.Info("超过设备数上限,注销最久未活跃的会话",
+			logger.String("student_id", studentId),
+			logger.String("ssid", sessions[i].Ssid))
+		errs = append(errs, r.revoke(ctx, studentId, sessions[i].Ssid))
+	}
+	return errors.Join(errs...)
+}
+
+// Sessions 列出学号下所有未过期的会话,按最后活跃时间倒序
+func (r *RedisJWTHandler) Sessions(ctx *gin.Context, studentId string) ([]Session, error) {
+	raw, err := r.cmd.HGetAll(ctx, r.sessionsKey(studentId)).Result()
+	if err != nil {
+		return nil, err
+	}
+	seen, err := r.cmd.HGetAll(ctx, r.lastSeenKey(studentId)).Result()
+	if err != nil {
+		return nil, err
+	}
+	userAgents, err := r.cmd.HGetAll(ctx, r.userAgentKey(studentId)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sessions := make([]Session, 0, len(raw))
+	var expired []string
+	for ssid, data := range raw {
+		var s Session
+		if err = json.Unmarshal([]byte(data), &s); err != nil || now.After(s.ExpiresAt) {
+			expired = append(expired, ssid)
+			continue
+		}
+		if ms, err := strconv.ParseInt(seen[ssid], 10, 64); err == nil {
+			s.LastSeen = time.UnixMilli(ms)
+		}
+		if ua, ok := userAgents[ssid]; ok {
+			s.UserAgent = ua
+		}
+		sessions = append(sessions, s)
+	}
+	// 顺手清理过期的会话,失败了下次再清
+	if len(expired) > 0 {
+		r.cmd.HDel(ctx, r.sessionsKey(studentId), expired...)
+		r.cmd.HDel(ctx, r.lastSeenKey(studentId), expired...)
+		r.cmd.HDel(ctx, r.userAgentKey(studentId), expired...)
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeen.After(sessions[j].LastSeen)
+	})
+	return sessions, nil
+}
+
+// RevokeSession 注销学号下的某个会话,不属于这个学号的会话返回 ErrSessionNotFound
+func (r *RedisJWTHandler) RevokeSession(ctx *gin.Context, studentId string, ssid string) error {
+	ok, err := r.cmd.HExists(ctx, r.sessionsKey(studentId), ssid).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrSessionNotFound
+	}
+	return r.revoke(ctx, studentId, ssid)
+}
+
+// RevokeOtherSessions 注销除了 current 以外的所有会话
+func (r *RedisJWTHandler) RevokeOtherSessions(ctx *gin.Context, studentId string, current string) error {
+	ssids, err := r.cmd.HKeys(ctx, r.sessionsKey(studentId)).Result()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, ssid := range ssids {
+		if ssid != current {
+			errs = append(errs, r.revoke(ctx, studentId, ssid))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Touch 更新会话的最后活跃时间,User-Agent 和登录时不一样的话也记下来
+// 每个请求都会调用,同一个会话在 touchInterval 内只写一次 redis,设备列表里的最后活跃时间精确到分钟就够了
+func (r *RedisJWTHandler) Touch(ctx *gin.Context, uc UserClaims) error {
+	ua := ctx.GetHeader("User-Agent")
+	if !r.touched.Allow(uc.Ssid, ua, time.Now()) {
+		return nil
+	}
+	pipe := r.cmd.Pipeline()
+	pipe.HSet(ctx, r.lastSeenKey(uc.StudentId), uc.Ssid, time.Now().UnixMilli())
+	// 会话列表上线之前登录的会话,或者索引已经过期的,这里是第一次写,同样要带上过期时间
+	pipe.Expire(ctx, r.lastSeenKey(uc.StudentId), r.rcExpiration)
+	if ua != uc.UserAgent {
+		pipe.HSet(ctx, r.userAgentKey(uc.StudentId), uc.Ssid, ua)
+		pipe.Expire(ctx, r.userAgentKey(uc.StudentId), r.rcExpiration)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
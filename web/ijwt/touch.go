@@ -0,0 +1,51 @@
+package ijwt
+
+import (
+	"sync"
+	"time"
+)
+
+// touchInterval 同一个会话两次写入最后活跃时间的最小间隔
+const touchInterval = time.Minute
+
+// maxTouchEntries 本地最多记住多少个会话的写入时间,超出之后清理一遍
+const maxTouchEntries = 100000
+
+type touchEntry struct {
+	at        time.Time
+	userAgent string
+}
+
+// touchThrottle 进程内记录每个会话上次写入的时间,多个实例各自限流,最多也就每个实例每分钟写一次
+type touchThrottle struct {
+	mu      sync.Mutex
+	entries map[string]touchEntry // ssid -> 上次写入
+}
+
+func newTouchThrottle() *touchThrottle {
+	return &touchThrottle{entries: make(map[string]touchEntry)}
+}
+
+// Allow 距离上次写入超过 touchInterval 或者 User-Agent 变了才需要写,
+// 在写之前就记下来,redis 出问题的时候也不会每个请求都去重试
+func (t *touchThrottle) Allow(ssid string, userAgent string, now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e, ok := t.entries[ssid]
+	if ok && e.userAgent == userAgent && now.Sub(e.at) < touchInterval {
+		return false
+	}
+	if !ok && len(t.entries) >= maxTouchEntries {
+		for k, v := range t.entries {
+			if now.Sub(v.at) >= touchInterval {
+				delete(t.entries, k)
+			}
+		}
+		// 一分钟内活跃的会话还是这么多的话就不记了,这些会话每次都写
+		if len(t.entries) >= maxTouchEntries {
+			return true
+		}
+	}
+	t.entries[ssid] = touchEntry{at: now, userAgent: userAgent}
+	return true
+}
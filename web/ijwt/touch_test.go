@@ -0,0 +1,30 @@
+package ijwt
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTouchThrottle(t *testing.T) {
+	start := time.Now()
+	tt := newTouchThrottle()
+	steps := []struct {
+		name      string
+		ssid      string
+		userAgent string
+		at        time.Duration
+		want      bool
+	}{
+		{name: "First request", ssid: "a", userAgent: "ua", want: true},
+		{name: "Within interval", ssid: "a", userAgent: "ua", at: 30 * time.Second},
+		{name: "Other session", ssid: "b", userAgent: "ua", at: 30 * time.Second, want: true},
+		{name: "User-Agent changed", ssid: "a", userAgent: "ua2", at: 40 * time.Second, want: true},
+		{name: "Within interval after change", ssid: "a", userAgent: "ua2", at: 90 * time.Second},
+		{name: "Interval passed", ssid: "a", userAgent: "ua2", at: 100 * time.Second, want: true},
+	}
+	for _, s := range steps {
+		if got := tt.Allow(s.ssid, s.userAgent, start.Add(s.at)); got != s.want {
+			t.Errorf("%s: Allow() = %v, want %v", s.name, got, s.want)
+		}
+	}
+}
@@ -1,23 +1,38 @@
 package ijwt
 
 import (
+	"github.com/asynccnu/bff/pkg/jwtx"
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"time"
 )
 
 //go:generate mockgen -source=./types.go -package=ijwtmocks -destination=./mocks/ijwt.mock.go Handler
 type Handler interface {
 	ClearToken(ctx *gin.Context) error
 	ExtractToken(ctx *gin.Context) string
+	ExtractRefreshToken(ctx *gin.Context) string
+	CheckCSRF(ctx *gin.Context) error
 	SetLoginToken(ctx *gin.Context, studentId string, password string) error
 	SetJWTToken(ctx *gin.Context, cp ClaimParams) error
+	RotateRefreshToken(ctx *gin.Context, rc RefreshClaims) error
 	CheckSession(ctx *gin.Context, ssid string) (bool, error)
-	JWTKey() []byte
-	RCJWTKey() []byte
+	VerifyDevice(ctx *gin.Context, userAgent string, d Device) error
+	Touch(ctx *gin.Context, uc UserClaims) error
+	Sessions(ctx *gin.Context, studentId string) ([]Session, error)
+	RevokeSession(ctx *gin.Context, studentId string, ssid string) error
+	RevokeOtherSessions(ctx *gin.Context, studentId string, current string) error
+	Credential(ctx *gin.Context, uc UserClaims) (Credential, error)
+	MigrateCredential(ctx *gin.Context, ssid string, cred Credential) error
+	AccessKeyfunc() jwt.Keyfunc
+	RefreshKeyfunc() jwt.Keyfunc
+	JWKS() jwtx.JWKS
+	Impersonate(ctx *gin.Context, admin UserClaims, studentId string) (string, time.Time, error)
 }
 
 type ClaimParams struct {
 	StudentId string
-	Password  string
 	Ssid      string
 	UserAgent string
+	Device
 }
@@ -1,32 +1,42 @@
 package infoSum
 
 import (
-	"fmt"
 	InfoSumv1 "github.com/asynccnu/be-api/gen/proto/infoSum/v1"
 	"github.com/asynccnu/bff/errs"
 	"github.com/asynccnu/bff/pkg/ginx"
 	"github.com/asynccnu/bff/web"
 	"github.com/asynccnu/bff/web/department"
 	"github.com/asynccnu/bff/web/ijwt"
+	"github.com/asynccnu/bff/web/rbac/perm"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/copier"
+	"net/http"
 )
 
 type InfoSumHandler struct {
-	InfoSumClient  InfoSumv1.InfoSumServiceClient
-	Administrators map[string]struct{}
+	InfoSumClient InfoSumv1.InfoSumServiceClient
+	Authorizer    web.Authorizer
 }
 
 func NewInfoSumHandler(InfoSumClient InfoSumv1.InfoSumServiceClient,
-	administrators map[string]struct{}) *InfoSumHandler {
-	return &InfoSumHandler{InfoSumClient: InfoSumClient, Administrators: administrators}
+	authorizer web.Authorizer) *InfoSumHandler {
+	return &InfoSumHandler{InfoSumClient: InfoSumClient, Authorizer: authorizer}
 }
 
 func (h *InfoSumHandler) RegisterRoutes(s *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
 	sg := s.Group("/InfoSum")
 	sg.GET("/getInfoSums", ginx.Wrap(h.GetInfoSums))
-	sg.POST("/saveInfoSum", authMiddleware, ginx.WrapClaimsAndReq(h.SaveInfoSum))
-	sg.DELETE("/delInfoSum", authMiddleware, ginx.WrapClaimsAndReq(h.DelInfoSum))
+	sg.POST("/saveInfoSum", authMiddleware, h.Authorizer.RequirePermission(perm.InfoSumWrite), ginx.WrapClaimsAndReq(h.SaveInfoSum))
+	sg.DELETE("/delInfoSum", authMiddleware, h.Authorizer.RequirePermission(perm.InfoSumWrite), ginx.WrapClaimsAndReq(h.DelInfoSum))
+}
+
+// AuthPolicies 信息整合列表游客也能看,增删可以用 API Key 调用
+func (h *InfoSumHandler) AuthPolicies() []web.RoutePolicy {
+	return []web.RoutePolicy{
+		{Method: http.MethodGet, Path: "/InfoSum/getInfoSums", Auth: web.AuthPublic},
+		{Method: http.MethodPost, Path: "/InfoSum/saveInfoSum", Auth: web.AuthRequired, Service: true},
+		{Method: http.MethodDelete, Path: "/InfoSum/delInfoSum", Auth: web.AuthRequired, Service: true},
+	}
 }
 
 // GetInfoSums 获取信息整合列表
@@ -65,10 +75,6 @@ func (h *InfoSumHandler) GetInfoSums(ctx *gin.Context) (web.Response, error) {
 // @Success 200 {object} web.Response "成功"
 // @Router /InfoSum/saveInfoSum [post]
 func (h *InfoSumHandler) SaveInfoSum(ctx *gin.Context, req SaveInfoSumRequest, uc ijwt.UserClaims) (web.Response, error) {
-	if !h.isAdmin(uc.StudentId) {
-		return web.Response{}, errs.ROLE_ERROR(fmt.Errorf("没有访问权限: %s", uc.StudentId))
-	}
-
 	_, err := h.InfoSumClient.SaveInfoSum(ctx, &InfoSumv1.SaveInfoSumRequest{
 		InfoSum: &InfoSumv1.InfoSum{
 			Id:          req.Id,
@@ -99,10 +105,6 @@ func (h *InfoSumHandler) SaveInfoSum(ctx *gin.Context, req SaveInfoSumRequest, u
 // @Success 200 {object} web.Response "成功"
 // @Router /InfoSum/delInfoSum [delete]
 func (h *InfoSumHandler) DelInfoSum(ctx *gin.Context, req department.DelDepartmentRequest, uc ijwt.UserClaims) (web.Response, error) {
-	if !h.isAdmin(uc.StudentId) {
-		return web.Response{}, errs.ROLE_ERROR(fmt.Errorf("没有访问权限: %s", uc.StudentId))
-	}
-
 	_, err := h.InfoSumClient.DelInfoSum(ctx, &InfoSumv1.DelInfoSumRequest{Id: req.Id})
 	if err != nil {
 		return web.Response{
@@ -114,8 +116,3 @@ func (h *InfoSumHandler) DelInfoSum(ctx *gin.Context, req department.DelDepartme
 		Msg: "Success",
 	}, nil
 }
-
-func (h *InfoSumHandler) isAdmin(studentId string) bool {
-	_, exists := h.Administrators[studentId]
-	return exists
-}
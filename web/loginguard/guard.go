@@ -0,0 +1,114 @@
+package loginguard
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"github.com/asynccnu/bff/config"
+	"github.com/asynccnu/bff/pkg/limiter"
+	"github.com/asynccnu/bff/pkg/logger"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+//go:embed lockout.lua
+var lockoutScript string
+
+// LoginGuard 防止有人通过 BFF 爆破 CCNU 的密码,按学号和 IP 限制登录尝试次数,连续输错密码之后锁定学号
+type LoginGuard struct {
+	cmd redis.Cmdable
+	rt  *config.Runtime
+	l   logger.Logger
+}
+
+func NewLoginGuard(cmd redis.Cmdable, rt *config.Runtime, l logger.Logger) *LoginGuard {
+	return &LoginGuard{cmd: cmd, rt: rt, l: l}
+}
+
+func (g *LoginGuard) lockKey(studentId string) string {
+	return fmt.Sprintf("ccnubox:login:lock:%s", studentId)
+}
+
+func (g *LoginGuard) failKey(studentId string) string {
+	return fmt.Sprintf("ccnubox:login:fail:%s", studentId)
+}
+
+func (g *LoginGuard) studentLimitKey(studentId string) string {
+	return fmt.Sprintf("ccnubox:ratelimit:login:sid:%s", studentId)
+}
+
+func (g *LoginGuard) ipLimitKey(ip string) string {
+	return fmt.Sprintf("ccnubox:ratelimit:login:ip:%s", ip)
+}
+
+// Allow 检查这次登录尝试是否放行,返回值大于 0 表示被拦下了,需要等待的时间
+// redis 出问题的时候和全局限流一样直接放行,不能因为防爆破把所有人都挡在外面
+func (g *LoginGuard) Allow(ctx context.Context, studentId string, ip string) time.Duration {
+	cfg := g.rt.Load().LoginGuard
+
+	if cfg.Lockout.Threshold > 0 {
+		ttl, err := g.cmd.PTTL(ctx, g.lockKey(studentId)).Result()
+		if err != nil {
+			g.l.Warn("查询登录锁定状态失败,放行请求", logger.Error(err), logger.String("studentId", studentId))
+			return 0
+		}
+		if ttl > 0 {
+			return ttl
+		}
+	}
+
+	limits := []struct {
+		cfg config.RateLimitConfig
+		key string
+	}{
+		{cfg: cfg.PerStudent, key: g.studentLimitKey(studentId)},
+		{cfg: cfg.PerIP, key: g.ipLimitKey(ip)},
+	}
+	for _, limit := range limits {
+		if !limit.cfg.Enabled {
+			continue
+		}
+		limited, err := limiter.NewRedisSlideWindowLimiter(g.cmd, limit.cfg.Interval, limit.cfg.Threshold).Limit(ctx, limit.key)
+		if err != nil {
+			g.l.Warn("登录限流器出错,放行请求", logger.Error(err), logger.String("key", limit.key))
+			continue
+		}
+		if limited {
+			// 滑动窗口不知道最早的一次尝试什么时候过期,按整个窗口算,客户端最多多等一会
+			return limit.cfg.Interval
+		}
+	}
+	return 0
+}
+
+// Fail 记录一次密码错误,只有 USER_SID_Or_PASSPORD_ERROR 才算,CCNU 本身出问题不能算到用户头上
+func (g *LoginGuard) Fail(ctx context.Context, studentId string, ip string) {
+	lockout := g.rt.Load().LoginGuard.Lockout
+	if lockout.Threshold <= 0 {
+		return
+	}
+	lock, err := g.cmd.Eval(ctx, lockoutScript, []string{g.failKey(studentId), g.lockKey(studentId)},
+		lockout.Window.Milliseconds(), lockout.Threshold, lockout.BaseDuration.Milliseconds(), lockout.MaxDuration.Milliseconds()).Int64()
+	if err != nil {
+		g.l.Warn("记录登录失败次数失败", logger.Error(err), logger.String("studentId", studentId))
+		return
+	}
+	if lock > 0 {
+		g.l.Warn("密码连续错误,锁定学号",
+			logger.String("studentId", studentId),
+			logger.String("ip", ip),
+			logger.String("duration", time.Duration(lock*int64(time.Millisecond)).String()))
+	}
+}
+
+// Succeed 登录成功之后清空失败次数
+func (g *LoginGuard) Succeed(ctx context.Context, studentId string) {
+	if err := g.cmd.Del(ctx, g.failKey(studentId)).Err(); err != nil {
+		g.l.Warn("清空登录失败次数失败", logger.Error(err), logger.String("studentId", studentId))
+	}
+}
+
+// Unlock 解除学号的锁定,同时清空失败次数和这个学号的限流窗口,给管理员处理误伤用
+func (g *LoginGuard) Unlock(ctx context.Context, studentId string) error {
+	return g.cmd.Del(ctx, g.lockKey(studentId), g.failKey(studentId), g.studentLimitKey(studentId)).Err()
+}
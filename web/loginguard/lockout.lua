@@ -0,0 +1,24 @@
+-- 记录一次密码错误,连续错误达到阈值之后锁定学号,之后每多错一次锁定时间翻倍
+local failKey = KEYS[1]
+local lockKey = KEYS[2]
+-- 失败计数的有效期
+local window = tonumber(ARGV[1])
+-- 阈值
+local threshold = tonumber(ARGV[2])
+local base = tonumber(ARGV[3])
+local max = tonumber(ARGV[4])
+
+local cnt = redis.call('INCR', failKey)
+if cnt < threshold then
+    redis.call('PEXPIRE', failKey, window)
+    return 0
+end
+
+local lock = base * 2 ^ (cnt - threshold)
+if lock > max then
+    lock = max
+end
+redis.call('SET', lockKey, cnt, 'PX', lock)
+-- 锁定期间失败次数不能过期,否则解锁后再输错又从头开始翻倍
+redis.call('PEXPIRE', failKey, lock + window)
+return lock
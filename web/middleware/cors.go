@@ -1,35 +1,30 @@
 package middleware
 
 import (
+	"github.com/asynccnu/bff/config"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
-	"strings"
 	"time"
 )
 
-func NewCorsMiddleware() *CorsMiddleware {
-	return &CorsMiddleware{}
+func NewCorsMiddleware(rt *config.Runtime) *CorsMiddleware {
+	return &CorsMiddleware{rt: rt}
 }
 
-type CorsMiddleware struct{}
+type CorsMiddleware struct {
+	rt *config.Runtime
+}
 
 func (c *CorsMiddleware) MiddlewareFunc() gin.HandlerFunc {
 	return cors.New(cors.Config{
 		// 允许的请求头
-		AllowHeaders: []string{"Content-Type", "Authorization"},
+		AllowHeaders: []string{"Content-Type", "Authorization", "X-Device-Name", "X-Client-Type", "X-Device-Id", "X-Device-Key", "X-Device-Timestamp", "X-Device-Signature", "X-Auth-Mode", "X-CSRF-Token"},
 		// 添加到响应头去,默认的响应头是不能够显示自定义的部分的
-		ExposeHeaders: []string{"x-jwt-token", "x-refresh-token"},
+		ExposeHeaders: []string{"x-jwt-token", "x-refresh-token", "Retry-After"},
 		// 是否允许携带凭证（如 Cookies）
 		AllowCredentials: true,
-		// 解决跨域问题,当是以localhost或者bigdust.space开头的时候就允许跨域
-		AllowOriginFunc: func(origin string) bool {
-			//检测请求来源是否以localhost开头
-			if strings.HasPrefix(origin, "localhost") {
-				return true
-			}
-			//检测请求来源是否以bigdust.space开头 TODO 记得改成木犀的
-			return strings.Contains(origin, "")
-		},
+		// 允许跨域的来源由配置文件中的 cors.allowOrigins 决定,支持热更新
+		AllowOriginFunc: c.rt.AllowOrigin,
 
 		// 预检请求的缓存时间
 		MaxAge: 12 * time.Hour,
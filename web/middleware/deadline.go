@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"github.com/asynccnu/bff/config"
+	"github.com/asynccnu/bff/pkg/grpcx"
+	"github.com/gin-gonic/gin"
+	"strings"
+	"time"
+)
+
+// DeadlineMiddleware 按路由给请求设置时间预算,handler 把 ctx 传给 grpc 客户端后这个 deadline 会一直生效
+// 需要 gin.Engine 开启 ContextWithFallback,否则 gin.Context 不会暴露 Request 上的 deadline
+type DeadlineMiddleware struct {
+	cfg config.DeadlineConfig
+}
+
+func NewDeadlineMiddleware(conf *config.Config) *DeadlineMiddleware {
+	return &DeadlineMiddleware{cfg: conf.Deadline}
+}
+
+func (m *DeadlineMiddleware) MiddlewareFunc() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		newCtx, cancel := grpcx.WithBudget(ctx.Request.Context(), m.timeout(ctx))
+		defer cancel()
+		ctx.Request = ctx.Request.WithContext(newCtx)
+		ctx.Next()
+	}
+}
+
+func (m *DeadlineMiddleware) timeout(ctx *gin.Context) time.Duration {
+	path := ctx.FullPath()
+	for _, route := range m.cfg.Routes {
+		if route.Path != path || (route.Method != "" && !strings.EqualFold(route.Method, ctx.Request.Method)) {
+			continue
+		}
+		matched := true
+		for k, v := range route.Query {
+			if queryFold(ctx, k) != v {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return route.Timeout
+		}
+	}
+	return m.cfg.Default
+}
+
+// queryFold 不区分大小写地取查询参数,配置里的 query 经过 viper 之后 key 全部变成了小写,
+// 按原样去取的话 studentId 这种驼峰的参数永远匹配不上
+func queryFold(ctx *gin.Context, key string) string {
+	if v, ok := ctx.GetQuery(key); ok {
+		return v
+	}
+	for k, vs := range ctx.Request.URL.Query() {
+		if strings.EqualFold(k, key) && len(vs) > 0 {
+			return vs[0]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"github.com/asynccnu/bff/config"
+	"github.com/gin-gonic/gin"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDeadlineTimeout(t *testing.T) {
+	m := NewDeadlineMiddleware(&config.Config{Deadline: config.DeadlineConfig{
+		Default: 30 * time.Second,
+		Routes: []config.RouteDeadline{
+			// viper 读出来的 key 是小写的
+			{Path: "/api/v1/grade/get", Method: "GET", Query: map[string]string{"studentid": "2023000000"}, Timeout: 5 * time.Second},
+			{Path: "/api/v1/banner/getBanners", Timeout: time.Second},
+		},
+	}})
+	engine := gin.New()
+	var got time.Duration
+	handle := func(ctx *gin.Context) { got = m.timeout(ctx) }
+	engine.GET("/api/v1/grade/get", handle)
+	engine.POST("/api/v1/grade/get", handle)
+	engine.GET("/api/v1/banner/getBanners", handle)
+
+	tests := []struct {
+		method string
+		target string
+		want   time.Duration
+	}{
+		{method: "GET", target: "/api/v1/grade/get?studentId=2023000000", want: 5 * time.Second},
+		{method: "GET", target: "/api/v1/grade/get?studentid=2023000000", want: 5 * time.Second},
+		{method: "GET", target: "/api/v1/grade/get?studentId=2023000001", want: 30 * time.Second},
+		{method: "POST", target: "/api/v1/grade/get?studentId=2023000000", want: 30 * time.Second},
+		{method: "GET", target: "/api/v1/banner/getBanners", want: time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			got = 0
+			engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.target, nil))
+			if got != tt.want {
+				t.Errorf("timeout = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
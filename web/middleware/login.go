@@ -1,79 +1,162 @@
 package middleware
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"github.com/asynccnu/bff/config"
 	"github.com/asynccnu/bff/errs"
 	"github.com/asynccnu/bff/pkg/ginx"
+	"github.com/asynccnu/bff/pkg/logger"
+	"github.com/asynccnu/bff/pkg/prometheusx"
+	"github.com/asynccnu/bff/web"
+	"github.com/asynccnu/bff/web/apikey"
 	"github.com/asynccnu/bff/web/ijwt"
-	"github.com/ecodeclub/ekit/set"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
-	"strings"
+	"net/http"
+	"path"
+	"sync/atomic"
+	"time"
 )
 
+// ApiKeyAuthenticator 校验 X-Api-Key 请求头,由 apikey.Service 实现
+type ApiKeyAuthenticator interface {
+	Authenticate(ctx context.Context, raw string) (ijwt.UserClaims, error)
+}
+
+// impersonationModule 模拟登录期间的请求在审计日志里的模块名
+const impersonationModule = "impersonation"
+
 type LoginMiddleware struct {
-	allowRestrictedAccessPaths set.Set[string]
+	// 按注册顺序匹配,第一个匹配上的生效,只在启动时写入
+	policies []web.RoutePolicy
 	ijwt.Handler
+	apiKeys    ApiKeyAuthenticator
+	auditor    Auditor
+	rt         *config.Runtime
+	l          logger.Logger
+	prometheus *prometheusx.PrometheusCounter
+	revoked    *revokedCache
+	// 上次打降级告警日志的时间,redis 故障期间每个请求都会降级,日志需要限流
+	lastAlert atomic.Int64
 }
 
-func NewLoginMiddleWare(hdl ijwt.Handler) *LoginMiddleware {
-	s := set.NewMapSet[string](3)
-	s.Add("/evaluations/list/all")
-	l := &LoginMiddleware{
-		allowRestrictedAccessPaths: s,
-		Handler:                    hdl,
+func NewLoginMiddleWare(hdl ijwt.Handler, apiKeys ApiKeyAuthenticator, auditor Auditor, rt *config.Runtime, l logger.Logger, prometheus *prometheusx.PrometheusCounter) *LoginMiddleware {
+	m := &LoginMiddleware{
+		Handler:    hdl,
+		apiKeys:    apiKeys,
+		auditor:    auditor,
+		rt:         rt,
+		l:          l,
+		prometheus: prometheus,
+		revoked:    newRevokedCache(),
 	}
-	return l
+	return m
 }
 
-func (m *LoginMiddleware) allowRestrictedAccess(path string) bool {
-	if m.allowRestrictedAccessPaths.Exist(path) {
-		return true
-	}
-	parts := strings.Split(strings.Trim(path, "/"), "/")
-	if len(parts) == 3 && parts[0] == "evaluations" && parts[2] == "detail" {
-		return true
+// AddPolicies 添加路由的登录要求,prefix 是 handler 注册路由时所在分组的路径
+func (m *LoginMiddleware) AddPolicies(prefix string, policies ...web.RoutePolicy) error {
+	for _, p := range policies {
+		p.Path = path.Join(prefix, p.Path)
+		if p.Auth == "" {
+			return fmt.Errorf("路由 %s: 没有声明登录要求", p.Path)
+		}
+		if _, err := path.Match(p.Path, ""); err != nil {
+			return fmt.Errorf("路由 %s: %w", p.Path, err)
+		}
+		m.policies = append(m.policies, p)
 	}
+	return nil
+}
+
+// Policy 查询路由的登录要求,fullPath 是 gin 注册时的路由(ctx.FullPath()),不是请求的实际路径
+func (m *LoginMiddleware) Policy(method string, fullPath string) web.AuthPolicy {
+	return m.match(method, fullPath).Auth
+}
 
-	return false
+// match 第一个匹配上的声明,没有声明的路由必须登录,模拟登录也不能访问
+func (m *LoginMiddleware) match(method string, fullPath string) web.RoutePolicy {
+	for _, p := range m.policies {
+		if p.Method != "" && p.Method != method {
+			continue
+		}
+		if ok, _ := path.Match(p.Path, fullPath); ok {
+			return p
+		}
+	}
+	return web.RoutePolicy{Method: method, Path: fullPath, Auth: web.AuthRequired}
 }
 
 func (m *LoginMiddleware) MiddlewareFunc() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
-		// 放行游客可访问的路由，
-		uc, err := m.extractUserClaimsFromAuthorizationHeader(ctx)
-		if err == nil {
-			//设置claims
+		rule := m.match(ctx.Request.Method, ctx.FullPath())
+		policy := rule.Auth
+		// 公开的路由一般不挂登录中间件,挂了也直接放行
+		if policy == web.AuthPublic {
+			return
+		}
+		// 带了 API Key 的一定是内部调用方,不会再去看 Authorization,认证失败也不会降级成游客
+		if raw := ctx.GetHeader(apikey.Header); raw != "" {
+			// 普通接口只认学生自己的登录态,API Key 只能调声明过的管理接口
+			if !rule.Service {
+				ctx.Error(errs.ROLE_ERROR(fmt.Errorf("API Key 不能访问 %s %s", ctx.Request.Method, ctx.FullPath())))
+				return
+			}
+			uc, err := m.apiKeys.Authenticate(ctx, raw)
+			if err != nil {
+				ctx.Error(errs.UNAUTHORIED_ERROR(fmt.Errorf("API Key 认证失败: %w", err)))
+				return
+			}
 			ginx.SetClaims[ijwt.UserClaims](ctx, uc)
-		} else {
-			if m.allowRestrictedAccess(ctx.Request.URL.Path) {
-				ginx.SetClaims[ijwt.UserClaims](ctx, uc)
-			} else {
-				ctx.Error(errs.UNAUTHORIED_ERROR(errors.New("身份验证失败!")))
+			return
+		}
+
+		// cookie 会被浏览器自动带上,写操作要额外校验 csrf token,token 本身有没有效放到后面再看
+		if !isSafeMethod(ctx.Request.Method) {
+			if err := m.CheckCSRF(ctx); err != nil {
+				ctx.Error(errs.CSRF_ERROR(err))
+				return
+			}
+		}
+
+		uc, err := m.extractUserClaimsFromAuthorizationHeader(ctx)
+		switch {
+		case err == nil && uc.IsImpersonated():
+			// 管理员模拟登录只能访问声明过的查询接口,看了什么也要全部记下来
+			if !rule.Impersonable {
+				ctx.Error(errs.IMPERSONATION_READ_ONLY_ERROR(fmt.Errorf("%s 模拟 %s 时不能访问 %s %s",
+					uc.ImpersonatedBy, uc.StudentId, ctx.Request.Method, ctx.FullPath())))
 				return
 			}
+			ginx.SetClaims[ijwt.UserClaims](ctx, uc)
+			m.auditor.Record(ctx, impersonationModule)
+		case err == nil:
+			//设置claims
+			ginx.SetClaims[ijwt.UserClaims](ctx, uc)
+		case policy == web.AuthOptional:
+			// 游客,token 过期了也当游客处理,需要登录的操作由客户端自己去刷新
+			ginx.SetClaims[ijwt.UserClaims](ctx, ijwt.UserClaims{})
+		default:
+			ctx.Error(errs.UNAUTHORIED_ERROR(errors.New("身份验证失败!")))
 		}
 	}
 }
 
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
 func (m *LoginMiddleware) extractUserClaimsFromAuthorizationHeader(ctx *gin.Context) (ijwt.UserClaims, error) {
-	authCode := ctx.GetHeader("Authorization")
+	// Bearer xxxx,网页端的 cookie 模式放在 cookie 里
+	tokenStr := m.ExtractToken(ctx)
 	// 没token
-	if authCode == "" {
-		return ijwt.UserClaims{}, errors.New("authorization为空")
-	}
-	// Bearer xxxx
-	segs := strings.Split(authCode, " ")
-
-	if len(segs) != 2 {
-		return ijwt.UserClaims{}, errors.New("authorization为空格式不合理")
+	if tokenStr == "" {
+		return ijwt.UserClaims{}, errors.New("authorization为空或格式不合理")
 	}
-	tokenStr := segs[1]
 	uc := ijwt.UserClaims{}
-	token, err := jwt.ParseWithClaims(tokenStr, &uc, func(*jwt.Token) (interface{}, error) {
-		// 可以根据具体情况给出不同的key
-		return m.JWTKey(), nil
-	})
+	// 签名算法和密钥的选择见 ijwt.RedisJWTHandler
+	token, err := jwt.ParseWithClaims(tokenStr, &uc, m.AccessKeyfunc())
 	if err != nil {
 		return ijwt.UserClaims{}, err
 	}
@@ -82,17 +165,61 @@ func (m *LoginMiddleware) extractUserClaimsFromAuthorizationHeader(ctx *gin.Cont
 	}
 
 	// token有效
-	// User-Agent
-	if uc.UserAgent != ctx.GetHeader("User-Agent") {
-		// 大概率是攻击者才会进入这个分支
-		return ijwt.UserClaims{}, errors.New("User-Agent验证：不安全")
+	// 设备绑定,按客户端类型选择比较设备 ID 还是 User-Agent
+	if err = m.VerifyDevice(ctx, uc.UserAgent, uc.Device); err != nil {
+		return ijwt.UserClaims{}, err
 	}
 
-	ok, err := m.CheckSession(ctx, uc.Ssid)
-	if err != nil || ok {
-		// err如果是redis崩溃导致，考虑进行降级，不再验证是否退出 refresh_token降级的话收益会很少，因为是低频接口
-		// 这里 != nil 就是异常，可能崩溃，或连不上
-		return ijwt.UserClaims{}, errors.New("session检验：失败")
+	revoked, err := m.CheckSession(ctx, uc.Ssid)
+	switch {
+	case err != nil:
+		// 这里 != nil 就是异常，可能崩溃，或连不上,按配置的策略降级 refresh_token降级的话收益会很少，因为是低频接口,所以只在这里处理
+		if !m.degrade(uc, err) {
+			return ijwt.UserClaims{}, errors.New("session检验：失败")
+		}
+	case revoked:
+		// 记下来,redis 出问题的时候 cache 策略靠它拒绝已经注销的会话
+		m.revoked.Add(uc.Ssid, m.rt.Load().SessionCheck.CacheTTL)
+		return ijwt.UserClaims{}, errors.New("session检验：已注销")
+	}
+	// 更新设备列表里的最后活跃时间,失败了也不影响这次请求,同一个会话每分钟最多写一次
+	if err = m.Touch(ctx, uc); err != nil {
+		m.l.Warn("更新会话最后活跃时间失败",
+			logger.Error(err),
+			logger.String("ssid", uc.Ssid))
 	}
 	return uc, nil
 }
+
+// alertInterval 降级告警日志的最小间隔
+const alertInterval = 10 * time.Second
+
+// degrade 会话检查出错时按 sessionCheck.onError 决定是否放行
+func (m *LoginMiddleware) degrade(uc ijwt.UserClaims, err error) bool {
+	policy := m.rt.Load().SessionCheck.OnError
+	allow := false
+	switch policy {
+	case config.SessionCheckFailOpen:
+		allow = true
+	case config.SessionCheckFailCache:
+		allow = !m.revoked.Contains(uc.Ssid)
+	}
+
+	result := "rejected"
+	if allow {
+		result = "allowed"
+	}
+	m.prometheus.DegradedAuthCounter.WithLabelValues(policy, result).Inc()
+
+	// 告警规则匹配这条日志,auth_degraded_total 指标可以看到具体的量
+	now := time.Now().UnixNano()
+	last := m.lastAlert.Load()
+	if now-last >= int64(alertInterval) && m.lastAlert.CompareAndSwap(last, now) {
+		m.l.Error("[ALERT] 会话检查失败,已按降级策略处理",
+			logger.Error(err),
+			logger.String("policy", policy),
+			logger.String("result", result),
+			logger.String("ssid", uc.Ssid))
+	}
+	return allow
+}
@@ -0,0 +1,229 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"github.com/asynccnu/bff/config"
+	"github.com/asynccnu/bff/pkg/errorx"
+	"github.com/asynccnu/bff/pkg/ginx"
+	"github.com/asynccnu/bff/pkg/jwtx"
+	"github.com/asynccnu/bff/pkg/logger"
+	"github.com/asynccnu/bff/pkg/prometheusx"
+	"github.com/asynccnu/bff/web"
+	"github.com/asynccnu/bff/web/apikey"
+	"github.com/asynccnu/bff/web/ijwt"
+	ijwtmocks "github.com/asynccnu/bff/web/ijwt/mocks"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSessionCheckDegrade(t *testing.T) {
+	keys := jwtx.NewHMACKeySet([]byte("0123456789abcdef0123456789abcdef"))
+	token, err := keys.Sign(ijwt.UserClaims{StudentId: "2023000000", Ssid: "ssid", UserAgent: "ua"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		policy      string
+		seenRevoked bool // redis 故障之前已经确认过这个会话被注销了
+		wantAllowed bool
+	}{
+		{name: "Fail closed", policy: config.SessionCheckFailClosed},
+		{name: "Fail open", policy: config.SessionCheckFailOpen, wantAllowed: true},
+		{name: "Cache allows unknown session", policy: config.SessionCheckFailCache, wantAllowed: true},
+		{name: "Cache rejects revoked session", policy: config.SessionCheckFailCache, seenRevoked: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			hdl := ijwtmocks.NewMockHandler(ctrl)
+			hdl.EXPECT().ExtractToken(gomock.Any()).Return(token).AnyTimes()
+			hdl.EXPECT().AccessKeyfunc().Return(keys.Keyfunc("")).AnyTimes()
+			hdl.EXPECT().Touch(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+			hdl.EXPECT().VerifyDevice(gomock.Any(), "ua", gomock.Any()).Return(nil).AnyTimes()
+			if tt.seenRevoked {
+				hdl.EXPECT().CheckSession(gomock.Any(), "ssid").Return(true, nil)
+			}
+			hdl.EXPECT().CheckSession(gomock.Any(), "ssid").Return(false, errors.New("redis: connection refused"))
+
+			rt := config.NewRuntime(&config.RuntimeConfig{SessionCheck: config.SessionCheckConfig{OnError: tt.policy, CacheTTL: time.Minute}})
+			counter := &prometheusx.PrometheusCounter{
+				DegradedAuthCounter: prometheus.NewCounterVec(prometheus.CounterOpts{Name: "auth_degraded_total"}, []string{"policy", "result"}),
+			}
+			m := NewLoginMiddleWare(hdl, nil, nil, rt, logger.NewZapLogger(zap.NewNop()), counter)
+
+			extract := func() error {
+				ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+				ctx.Request = httptest.NewRequest("GET", "/users/sessions", nil)
+				ctx.Request.Header.Set("Authorization", "Bearer "+token)
+				ctx.Request.Header.Set("User-Agent", "ua")
+				_, err := m.extractUserClaimsFromAuthorizationHeader(ctx)
+				return err
+			}
+			if tt.seenRevoked {
+				if err := extract(); err == nil {
+					t.Fatal("revoked session should be rejected")
+				}
+			}
+			if err := extract(); (err == nil) != tt.wantAllowed {
+				t.Errorf("extract() error = %v, want allowed %v", err, tt.wantAllowed)
+			}
+		})
+	}
+}
+
+func TestImpersonation(t *testing.T) {
+	keys := jwtx.NewHMACKeySet([]byte("0123456789abcdef0123456789abcdef"))
+	token, err := keys.Sign(ijwt.UserClaims{StudentId: "2023000000", Ssid: "ssid", UserAgent: "ua", ImpersonatedBy: "2020000000"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method   string
+		target   string
+		wantCode int // 0 表示放行
+		audited  bool
+	}{
+		{method: "GET", target: "/api/v1/users/sessions", audited: true},
+		{method: "POST", target: "/api/v1/card/getRecords", audited: true},
+		// 没有声明的接口
+		{method: "PUT", target: "/api/v1/users/profile", wantCode: http.StatusForbidden},
+		{method: "DELETE", target: "/api/v1/users/sessions/x", wantCode: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			hdl := ijwtmocks.NewMockHandler(ctrl)
+			hdl.EXPECT().ExtractToken(gomock.Any()).Return(token).AnyTimes()
+			hdl.EXPECT().CheckCSRF(gomock.Any()).Return(nil).AnyTimes()
+			hdl.EXPECT().AccessKeyfunc().Return(keys.Keyfunc("")).AnyTimes()
+			hdl.EXPECT().VerifyDevice(gomock.Any(), "ua", gomock.Any()).Return(nil).AnyTimes()
+			hdl.EXPECT().CheckSession(gomock.Any(), "ssid").Return(false, nil).AnyTimes()
+			hdl.EXPECT().Touch(gomock.Any(), gomock.Any()).Return(nil).AnyTimes()
+
+			audited := false
+			m := NewLoginMiddleWare(hdl, nil, auditorFunc(func(ctx *gin.Context, module string) {
+				uc, _ := ginx.GetClaims[ijwt.UserClaims](ctx)
+				if module != impersonationModule || uc.ImpersonatedBy != "2020000000" {
+					t.Errorf("Record(%s) with claims %+v", module, uc)
+				}
+				audited = true
+				ctx.Next()
+			}), config.NewRuntime(&config.RuntimeConfig{}), logger.NewZapLogger(zap.NewNop()), nil)
+			err := m.AddPolicies("/api/v1",
+				web.RoutePolicy{Method: "GET", Path: "/users/sessions", Auth: web.AuthRequired, Impersonable: true},
+				web.RoutePolicy{Method: "POST", Path: "/card/getRecords", Auth: web.AuthRequired, Impersonable: true},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			code := -1
+			engine := gin.New()
+			engine.Handle(tt.method, tt.target, m.MiddlewareFunc(), func(ctx *gin.Context) {
+				code = 0
+				if len(ctx.Errors) > 0 {
+					code = errorx.ToCustomError(ctx.Errors.Last().Err).HttpCode
+				}
+			})
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			req.Header.Set("User-Agent", "ua")
+			engine.ServeHTTP(httptest.NewRecorder(), req)
+
+			if audited != tt.audited {
+				t.Errorf("audited = %v, want %v", audited, tt.audited)
+			}
+			if code != tt.wantCode {
+				t.Errorf("status = %d, want %d", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+type apiKeyFunc func(ctx context.Context, raw string) (ijwt.UserClaims, error)
+
+func (f apiKeyFunc) Authenticate(ctx context.Context, raw string) (ijwt.UserClaims, error) {
+	return f(ctx, raw)
+}
+
+func TestApiKey(t *testing.T) {
+	tests := []struct {
+		method   string
+		target   string
+		wantCode int // 0 表示放行
+	}{
+		{method: "POST", target: "/api/v1/banner/saveBanner"},
+		{method: "GET", target: "/api/v1/users/sessions", wantCode: http.StatusForbidden},
+		{method: "GET", target: "/api/v1/banner/getBanners", wantCode: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.target, func(t *testing.T) {
+			// API Key 不看 Authorization,也不用校验 csrf,ijwt.Handler 一个方法都不会调
+			m := NewLoginMiddleWare(ijwtmocks.NewMockHandler(gomock.NewController(t)), apiKeyFunc(func(ctx context.Context, raw string) (ijwt.UserClaims, error) {
+				return ijwt.UserClaims{StudentId: "apikey:k1", ApiKeyId: "k1", Scopes: []string{"banner:write"}}, nil
+			}), nil, config.NewRuntime(&config.RuntimeConfig{}), logger.NewZapLogger(zap.NewNop()), nil)
+			err := m.AddPolicies("/api/v1",
+				web.RoutePolicy{Method: "GET", Path: "/banner/getBanners", Auth: web.AuthOptional},
+				web.RoutePolicy{Method: "POST", Path: "/banner/saveBanner", Auth: web.AuthRequired, Service: true},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			code := -1
+			engine := gin.New()
+			engine.Handle(tt.method, tt.target, m.MiddlewareFunc(), func(ctx *gin.Context) {
+				code = 0
+				if len(ctx.Errors) > 0 {
+					code = errorx.ToCustomError(ctx.Errors.Last().Err).HttpCode
+				}
+			})
+			req := httptest.NewRequest(tt.method, tt.target, nil)
+			req.Header.Set(apikey.Header, "raw")
+			engine.ServeHTTP(httptest.NewRecorder(), req)
+
+			if code != tt.wantCode {
+				t.Errorf("status = %d, want %d", code, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestRoutePolicy(t *testing.T) {
+	m := &LoginMiddleware{}
+	err := m.AddPolicies("/api/v1",
+		web.RoutePolicy{Method: "GET", Path: "/banner/getBanners", Auth: web.AuthOptional},
+		web.RoutePolicy{Path: "/statics/match/*", Auth: web.AuthOptional},
+		web.RoutePolicy{Path: "/users/:id/public", Auth: web.AuthPublic},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method   string
+		fullPath string
+		want     web.AuthPolicy
+	}{
+		{method: "GET", fullPath: "/api/v1/banner/getBanners", want: web.AuthOptional},
+		{method: "POST", fullPath: "/api/v1/banner/getBanners", want: web.AuthRequired},
+		{method: "GET", fullPath: "/api/v1/statics/match/labels", want: web.AuthOptional},
+		{method: "GET", fullPath: "/api/v1/statics/match/labels/x", want: web.AuthRequired},
+		{method: "GET", fullPath: "/api/v1/users/:id/public", want: web.AuthPublic},
+		{method: "GET", fullPath: "/api/v1/users/sessions", want: web.AuthRequired},
+	}
+	for _, tt := range tests {
+		if got := m.Policy(tt.method, tt.fullPath); got != tt.want {
+			t.Errorf("Policy(%s %s) = %s, want %s", tt.method, tt.fullPath, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"github.com/asynccnu/bff/errs"
+	"github.com/asynccnu/bff/pkg/ginx"
+	"github.com/asynccnu/bff/web/ijwt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// PermissionChecker 由 rbac.RBAC 实现
+type PermissionChecker interface {
+	HasPermission(ctx context.Context, studentId string, perm string) (bool, error)
+}
+
+// Auditor 记录需要权限的写操作,由 audit.Recorder 实现,Record 里面负责调用 ctx.Next()
+type Auditor interface {
+	Record(ctx *gin.Context, module string)
+}
+
+type PermissionMiddleware struct {
+	checker PermissionChecker
+	auditor Auditor
+}
+
+func NewPermissionMiddleware(checker PermissionChecker, auditor Auditor) *PermissionMiddleware {
+	return &PermissionMiddleware{checker: checker, auditor: auditor}
+}
+
+// RequirePermission 要求当前用户拥有 perm 权限,必须挂在登录中间件后面
+// 通过之后的写操作会记审计日志,模块就是权限里的资源部分
+func (m *PermissionMiddleware) RequirePermission(perm string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		// 登录中间件已经报错了,没有 claims 可以检查
+		if len(ctx.Errors) > 0 {
+			return
+		}
+		uc, err := ginx.GetClaims[ijwt.UserClaims](ctx)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		// 模拟登录的 token 只用来看学生看到的内容,管理接口一律不让进,不管被模拟的学生有没有权限
+		if uc.IsImpersonated() {
+			ctx.Error(errs.ROLE_ERROR(fmt.Errorf("模拟登录不能访问管理接口: %s 模拟 %s", uc.ImpersonatedBy, uc.StudentId)))
+			return
+		}
+		// API Key 只有创建时指定的 scopes,不参与 RBAC
+		ok := slices.Contains(uc.Scopes, perm)
+		if !uc.IsService() {
+			ok, err = m.checker.HasPermission(ctx, uc.StudentId, perm)
+			if err != nil {
+				ctx.Error(errs.CHECK_PERMISSION_ERROR(err))
+				return
+			}
+		}
+		if !ok {
+			ctx.Error(errs.ROLE_ERROR(fmt.Errorf("没有访问权限: %s 缺少 %s", uc.StudentId, perm)))
+			return
+		}
+		if m.auditor != nil && ctx.Request.Method != http.MethodGet && ctx.Request.Method != http.MethodHead {
+			module, _, _ := strings.Cut(perm, ":")
+			m.auditor.Record(ctx, module)
+			return
+		}
+		ctx.Next()
+	}
+}
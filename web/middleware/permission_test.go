@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"github.com/asynccnu/bff/pkg/errorx"
+	"github.com/asynccnu/bff/pkg/ginx"
+	"github.com/asynccnu/bff/web/ijwt"
+	"github.com/gin-gonic/gin"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type checkerFunc func(ctx context.Context, studentId string, perm string) (bool, error)
+
+func (f checkerFunc) HasPermission(ctx context.Context, studentId string, perm string) (bool, error) {
+	return f(ctx, studentId, perm)
+}
+
+type auditorFunc func(ctx *gin.Context, module string)
+
+func (f auditorFunc) Record(ctx *gin.Context, module string) {
+	f(ctx, module)
+}
+
+func TestRequirePermission(t *testing.T) {
+	tests := []struct {
+		name     string
+		authErr  bool     // 登录中间件已经报错
+		scopes   []string // 非空时以 API Key 的身份调用
+		admin    string   // 非空时是这个管理员模拟登录
+		has      bool
+		err      error
+		wantCode int // 0 表示放行
+		audited  bool
+	}{
+		{name: "Granted", has: true, audited: true},
+		{name: "Denied", wantCode: http.StatusForbidden},
+		{name: "Checker error", err: errors.New("redis: connection refused"), wantCode: http.StatusInternalServerError},
+		{name: "Not logged in", authErr: true, has: true, wantCode: http.StatusUnauthorized},
+		{name: "Api key in scope", scopes: []string{"feed:publish"}, audited: true},
+		{name: "Api key out of scope", scopes: []string{"banner:write"}, has: true, wantCode: http.StatusForbidden},
+		{name: "Impersonated", admin: "2020000000", has: true, wantCode: http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			called, audited := false, false
+			m := NewPermissionMiddleware(checkerFunc(func(_ context.Context, studentId string, perm string) (bool, error) {
+				called = true
+				if studentId != "2023000000" || perm != "feed:publish" {
+					t.Errorf("HasPermission(%s, %s)", studentId, perm)
+				}
+				return tt.has, tt.err
+			}), auditorFunc(func(_ *gin.Context, module string) {
+				if module != "feed" {
+					t.Errorf("Record(%s), want feed", module)
+				}
+				audited = true
+			}))
+
+			ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+			ctx.Request = httptest.NewRequest("POST", "/feed/publicMuxiOfficialMSG", nil)
+			if tt.authErr {
+				ctx.Error(errorx.New(http.StatusUnauthorized, 0, "", "", nil))
+			} else if tt.admin != "" {
+				ginx.SetClaims(ctx, ijwt.UserClaims{StudentId: "2023000000", ImpersonatedBy: tt.admin})
+			} else if tt.scopes != nil {
+				ginx.SetClaims(ctx, ijwt.UserClaims{StudentId: "apikey:k1", ApiKeyId: "k1", Scopes: tt.scopes})
+			} else {
+				ginx.SetClaims(ctx, ijwt.UserClaims{StudentId: "2023000000"})
+			}
+			m.RequirePermission("feed:publish")(ctx)
+
+			if (tt.authErr || tt.scopes != nil || tt.admin != "") && called {
+				t.Error("checker should not be called without claims, for api keys or under impersonation")
+			}
+			if audited != tt.audited {
+				t.Errorf("audited = %v, want %v", audited, tt.audited)
+			}
+			code := 0
+			if len(ctx.Errors) > 0 {
+				code = errorx.ToCustomError(ctx.Errors.Last().Err).HttpCode
+			}
+			if code != tt.wantCode {
+				t.Errorf("status = %d, want %d (errors: %v)", code, tt.wantCode, ctx.Errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"github.com/asynccnu/bff/config"
+	"github.com/asynccnu/bff/errs"
+	"github.com/asynccnu/bff/pkg/limiter"
+	"github.com/asynccnu/bff/pkg/logger"
+	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
+)
+
+type RateLimitMiddleware struct {
+	cmd redis.Cmdable
+	rt  *config.Runtime
+	l   logger.Logger
+}
+
+func NewRateLimitMiddleware(cmd redis.Cmdable, rt *config.Runtime, l logger.Logger) *RateLimitMiddleware {
+	return &RateLimitMiddleware{cmd: cmd, rt: rt, l: l}
+}
+
+// MiddlewareFunc 按 IP 做滑动窗口限流,阈值每次请求都从最新的配置里取,所以热更新后立即生效
+func (m *RateLimitMiddleware) MiddlewareFunc() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		cfg := m.rt.Load().RateLimit
+		if !cfg.Enabled {
+			ctx.Next()
+			return
+		}
+
+		lim := limiter.NewRedisSlideWindowLimiter(m.cmd, cfg.Interval, cfg.Threshold)
+		limited, err := lim.Limit(ctx, fmt.Sprintf("ccnubox:ratelimit:ip:%s", ctx.ClientIP()))
+		if err != nil {
+			// redis 出问题的时候不能把所有请求都拦下来,直接放行
+			m.l.Warn("限流器出错,放行请求", logger.Error(err), logger.String("ip", ctx.ClientIP()))
+			ctx.Next()
+			return
+		}
+		if limited {
+			ctx.Error(errs.TOO_MANY_REQUESTS_ERROR(errors.New("触发限流")))
+			return
+		}
+		ctx.Next()
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+)
+
+// maxRevokedCacheSize 本地最多记住多少个已注销的会话,防止内存无限增长
+const maxRevokedCacheSize = 100000
+
+// revokedCache 进程内记录最近确认过已注销的会话,redis 不可用的时候用来兜底
+type revokedCache struct {
+	mu      sync.Mutex
+	entries map[string]time.Time // ssid -> 过期时间
+}
+
+func newRevokedCache() *revokedCache {
+	return &revokedCache{entries: make(map[string]time.Time)}
+}
+
+func (c *revokedCache) Add(ssid string, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if len(c.entries) >= maxRevokedCacheSize {
+		for k, exp := range c.entries {
+			if now.After(exp) {
+				delete(c.entries, k)
+			}
+		}
+		// 清理之后还是满的就不记了,最多是降级期间这个会话被放行
+		if len(c.entries) >= maxRevokedCacheSize {
+			return
+		}
+	}
+	c.entries[ssid] = now.Add(ttl)
+}
+
+func (c *revokedCache) Contains(ssid string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	exp, ok := c.entries[ssid]
+	if ok && time.Now().After(exp) {
+		delete(c.entries, ssid)
+		return false
+	}
+	return ok
+}
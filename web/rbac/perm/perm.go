@@ -0,0 +1,58 @@
+// Package perm 权限和角色名的定义,不依赖任何其它包,config 启动时校验 rbac 配置也要用到
+package perm
+
+import (
+	"regexp"
+	"slices"
+)
+
+// 权限的格式是 资源:操作,新增需要鉴权的接口时在这里加上对应的权限
+const (
+	All             = "*" // 全部权限
+	AdminRead       = "admin:read"
+	LoginUnlock     = "login:unlock"
+	RBACManage      = "rbac:manage"
+	ApiKeyManage    = "apikey:manage"
+	AuditRead       = "audit:read"
+	BannerWrite     = "banner:write"
+	CalendarWrite   = "calendar:write"
+	DepartmentWrite = "department:write"
+	WebsiteWrite    = "website:write"
+	InfoSumWrite    = "infosum:write"
+	FeedPublish     = "feed:publish"
+	FAQWrite        = "faq:write"
+	StaticWrite     = "static:write"
+	UserImpersonate = "user:impersonate"
+)
+
+// List 所有已知的权限,给角色授权的时候只能从这里面选
+var List = []string{
+	All,
+	AdminRead,
+	LoginUnlock,
+	RBACManage,
+	ApiKeyManage,
+	AuditRead,
+	BannerWrite,
+	CalendarWrite,
+	DepartmentWrite,
+	WebsiteWrite,
+	InfoSumWrite,
+	FeedPublish,
+	FAQWrite,
+	StaticWrite,
+	UserImpersonate,
+}
+
+// Known 是否是已知的权限
+func Known(p string) bool {
+	return slices.Contains(List, p)
+}
+
+// rolePattern viper 会把配置里 map 的 key 转成小写,接口里也只允许小写,两边才能对得上
+var rolePattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// ValidRole 角色名是否合法
+func ValidRole(role string) bool {
+	return rolePattern.MatchString(role)
+}
@@ -0,0 +1,166 @@
+package rbac
+
+import (
+	"errors"
+	"github.com/asynccnu/bff/errs"
+	"github.com/asynccnu/bff/pkg/auditx"
+	"github.com/asynccnu/bff/pkg/ginx"
+	"github.com/asynccnu/bff/web"
+	"github.com/asynccnu/bff/web/ijwt"
+	"github.com/asynccnu/bff/web/rbac/perm"
+	"github.com/gin-gonic/gin"
+	"sort"
+)
+
+// RBACHandler 管理角色和授权
+type RBACHandler struct {
+	svc        *RBAC
+	Authorizer web.Authorizer
+}
+
+func NewRBACHandler(svc *RBAC, authorizer web.Authorizer) *RBACHandler {
+	return &RBACHandler{svc: svc, Authorizer: authorizer}
+}
+
+func (h *RBACHandler) RegisterRoutes(s *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
+	sg := s.Group("/admin/rbac", authMiddleware, h.Authorizer.RequirePermission(perm.RBACManage))
+	sg.GET("/roles", ginx.WrapClaims(h.GetRoles))
+	sg.PUT("/roles/:role", ginx.WrapClaimsAndReq(h.SaveRole))
+	sg.DELETE("/roles/:role", ginx.WrapClaims(h.DeleteRole))
+	sg.GET("/users/:studentId/roles", ginx.WrapClaims(h.GetUserRoles))
+	sg.PUT("/users/:studentId/roles", ginx.WrapClaimsAndReq(h.SetUserRoles))
+}
+
+// AuthPolicies 角色管理可以用 API Key 调用
+func (h *RBACHandler) AuthPolicies() []web.RoutePolicy {
+	return []web.RoutePolicy{
+		{Path: "/admin/rbac/roles", Auth: web.AuthRequired, Service: true},
+		{Path: "/admin/rbac/roles/:role", Auth: web.AuthRequired, Service: true},
+		{Path: "/admin/rbac/users/:studentId/roles", Auth: web.AuthRequired, Service: true},
+	}
+}
+
+// GetRoles 获取所有角色
+// @Summary 获取所有角色
+// @Description 获取所有角色以及每个角色拥有的权限,同时返回所有可以授予的权限
+// @Tags rbac
+// @Produce json
+// @Success 200 {object} web.Response{data=GetRolesResp} "成功"
+// @Router /admin/rbac/roles [get]
+func (h *RBACHandler) GetRoles(ctx *gin.Context, uc ijwt.UserClaims) (web.Response, error) {
+	roles, err := h.svc.Roles(ctx)
+	if err != nil {
+		return web.Response{}, errs.GET_ROLES_ERROR(err)
+	}
+	resp := GetRolesResp{Roles: make([]RoleVo, 0, len(roles)), Permissions: perm.List}
+	for name, perms := range roles {
+		resp.Roles = append(resp.Roles, RoleVo{Name: name, Permissions: perms})
+	}
+	sort.Slice(resp.Roles, func(i, j int) bool { return resp.Roles[i].Name < resp.Roles[j].Name })
+	return web.Response{
+		Msg:  "Success",
+		Data: resp,
+	}, nil
+}
+
+// SaveRole 创建或者修改角色
+// @Summary 创建或者修改角色
+// @Description 覆盖角色拥有的权限,角色不存在时创建,角色名只能包含小写字母、数字、下划线和中划线
+// @Tags rbac
+// @Accept json
+// @Produce json
+// @Param role path string true "角色名"
+// @Param request body SaveRoleReq true "角色拥有的权限"
+// @Success 200 {object} web.Response "成功"
+// @Router /admin/rbac/roles/{role} [put]
+func (h *RBACHandler) SaveRole(ctx *gin.Context, req SaveRoleReq, uc ijwt.UserClaims) (web.Response, error) {
+	h.setRoleBefore(ctx, ctx.Param("role"))
+	err := h.svc.SaveRole(ctx, ctx.Param("role"), req.Permissions)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrInvalidRoleName), errors.Is(err, ErrUnknownPermission):
+		return web.Response{}, errs.INVALID_PARAM_VALUE_ERROR(err)
+	default:
+		return web.Response{}, errs.SAVE_ROLE_ERROR(err)
+	}
+	return web.Response{
+		Msg: "Success",
+	}, nil
+}
+
+// DeleteRole 删除角色
+// @Summary 删除角色
+// @Description 删除角色,已经分配了这个角色的用户会失去对应的权限
+// @Tags rbac
+// @Produce json
+// @Param role path string true "角色名"
+// @Success 200 {object} web.Response "成功"
+// @Router /admin/rbac/roles/{role} [delete]
+func (h *RBACHandler) DeleteRole(ctx *gin.Context, uc ijwt.UserClaims) (web.Response, error) {
+	h.setRoleBefore(ctx, ctx.Param("role"))
+	err := h.svc.DeleteRole(ctx, ctx.Param("role"))
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrRoleNotFound):
+		return web.Response{}, errs.ROLE_NOT_FOUND_ERROR(err)
+	default:
+		return web.Response{}, errs.SAVE_ROLE_ERROR(err)
+	}
+	return web.Response{
+		Msg: "Success",
+	}, nil
+}
+
+// GetUserRoles 获取用户的角色
+// @Summary 获取用户的角色
+// @Description 获取学号被分配的角色,不包括配置文件中的 administrators
+// @Tags rbac
+// @Produce json
+// @Param studentId path string true "学号"
+// @Success 200 {object} web.Response{data=UserRolesVo} "成功"
+// @Router /admin/rbac/users/{studentId}/roles [get]
+func (h *RBACHandler) GetUserRoles(ctx *gin.Context, uc ijwt.UserClaims) (web.Response, error) {
+	studentId := ctx.Param("studentId")
+	roles, err := h.svc.UserRoles(ctx, studentId)
+	if err != nil {
+		return web.Response{}, errs.GET_ROLES_ERROR(err)
+	}
+	return web.Response{
+		Msg:  "Success",
+		Data: UserRolesVo{StudentId: studentId, Roles: roles},
+	}, nil
+}
+
+// SetUserRoles 设置用户的角色
+// @Summary 设置用户的角色
+// @Description 覆盖学号被分配的角色,传空数组表示收回全部角色
+// @Tags rbac
+// @Accept json
+// @Produce json
+// @Param studentId path string true "学号"
+// @Param request body SetUserRolesReq true "角色列表"
+// @Success 200 {object} web.Response "成功"
+// @Router /admin/rbac/users/{studentId}/roles [put]
+func (h *RBACHandler) SetUserRoles(ctx *gin.Context, req SetUserRolesReq, uc ijwt.UserClaims) (web.Response, error) {
+	if roles, err := h.svc.UserRoles(ctx, ctx.Param("studentId")); err == nil {
+		auditx.SetBefore(ctx, SetUserRolesReq{Roles: roles})
+	}
+	err := h.svc.SetUserRoles(ctx, ctx.Param("studentId"), req.Roles)
+	switch {
+	case err == nil:
+	case errors.Is(err, ErrRoleNotFound):
+		return web.Response{}, errs.ROLE_NOT_FOUND_ERROR(err)
+	default:
+		return web.Response{}, errs.SAVE_ROLE_ERROR(err)
+	}
+	return web.Response{
+		Msg: "Success",
+	}, nil
+}
+
+// setRoleBefore 修改之前的权限记进审计日志,新建的角色没有
+func (h *RBACHandler) setRoleBefore(ctx *gin.Context, role string) {
+	if perms, err := h.svc.Role(ctx, role); err == nil {
+		auditx.SetBefore(ctx, SaveRoleReq{Permissions: perms})
+	}
+}
@@ -0,0 +1,24 @@
+package rbac
+
+type RoleVo struct {
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+}
+
+type GetRolesResp struct {
+	Roles       []RoleVo `json:"roles"`
+	Permissions []string `json:"permissions"` // 所有可以授予的权限
+}
+
+type SaveRoleReq struct {
+	Permissions []string `json:"permissions"`
+}
+
+type UserRolesVo struct {
+	StudentId string   `json:"student_id"`
+	Roles     []string `json:"roles"`
+}
+
+type SetUserRolesReq struct {
+	Roles []string `json:"roles"`
+}
@@ -0,0 +1,217 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/asynccnu/bff/config"
+	"github.com/asynccnu/bff/web/rbac/perm"
+	"github.com/redis/go-redis/v9"
+	"slices"
+	"sort"
+)
+
+var (
+	ErrUnknownPermission = errors.New("未知的权限")
+	ErrRoleNotFound      = errors.New("角色不存在")
+	ErrInvalidRoleName   = errors.New("非法的角色名")
+)
+
+const (
+	// 角色 -> 权限列表(json)
+	rolesKey = "ccnubox:rbac:roles"
+	// 学号 -> 角色列表(json)
+	assignmentsKey = "ccnubox:rbac:assignments"
+)
+
+// RBAC 角色和授权保存在 redis 里,runtime 配置里的 administrators 始终拥有全部权限,
+// 这样 redis 里的数据出问题的时候管理员也不会被锁在外面
+type RBAC struct {
+	cmd redis.Cmdable
+	rt  *config.Runtime
+}
+
+func NewRBAC(cmd redis.Cmdable, rt *config.Runtime) *RBAC {
+	return &RBAC{cmd: cmd, rt: rt}
+}
+
+// Bootstrap 把配置中的角色和授权写入 redis,已经存在的不会覆盖,管理接口做的修改重启之后依然有效
+func (r *RBAC) Bootstrap(ctx context.Context, cfg config.RBACConfig) error {
+	pipe := r.cmd.Pipeline()
+	for role, perms := range cfg.Roles {
+		if err := checkRole(role, perms); err != nil {
+			return fmt.Errorf("rbac.roles.%s: %w", role, err)
+		}
+		val, _ := json.Marshal(perms)
+		pipe.HSetNX(ctx, rolesKey, role, val)
+	}
+	for studentId, roles := range cfg.Assignments {
+		val, _ := json.Marshal(roles)
+		pipe.HSetNX(ctx, assignmentsKey, studentId, val)
+	}
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// HasPermission 判断学号是否拥有某个权限
+func (r *RBAC) HasPermission(ctx context.Context, studentId string, permission string) (bool, error) {
+	if r.rt.Load().IsAdmin(studentId) {
+		return true, nil
+	}
+	roles, err := r.UserRoles(ctx, studentId)
+	if err != nil || len(roles) == 0 {
+		return false, err
+	}
+	perms, err := r.rolePermissions(ctx, roles)
+	if err != nil {
+		return false, err
+	}
+	return slices.Contains(perms, permission) || slices.Contains(perms, perm.All), nil
+}
+
+// UserPermissions 学号的角色以及这些角色合起来的权限,administrators 的权限是 "*"
+func (r *RBAC) UserPermissions(ctx context.Context, studentId string) ([]string, []string, error) {
+	roles, err := r.UserRoles(ctx, studentId)
+	if err != nil {
+		return nil, nil, err
+	}
+	if r.rt.Load().IsAdmin(studentId) {
+		return roles, []string{perm.All}, nil
+	}
+	if len(roles) == 0 {
+		return roles, []string{}, nil
+	}
+	perms, err := r.rolePermissions(ctx, roles)
+	return roles, perms, err
+}
+
+// rolePermissions 多个角色的权限合并去重
+func (r *RBAC) rolePermissions(ctx context.Context, roles []string) ([]string, error) {
+	vals, err := r.cmd.HMGet(ctx, rolesKey, roles...).Result()
+	if err != nil {
+		return nil, err
+	}
+	res := []string{}
+	for _, val := range vals {
+		// 角色被删掉之后,已经分配了这个角色的学号不会自动清理,这里直接忽略
+		s, ok := val.(string)
+		if !ok {
+			continue
+		}
+		var perms []string
+		if err = json.Unmarshal([]byte(s), &perms); err != nil {
+			return nil, err
+		}
+		res = append(res, perms...)
+	}
+	return dedup(res), nil
+}
+
+// Roles 所有角色和对应的权限
+func (r *RBAC) Roles(ctx context.Context) (map[string][]string, error) {
+	vals, err := r.cmd.HGetAll(ctx, rolesKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string][]string, len(vals))
+	for role, val := range vals {
+		var perms []string
+		if err = json.Unmarshal([]byte(val), &perms); err != nil {
+			return nil, err
+		}
+		res[role] = perms
+	}
+	return res, nil
+}
+
+// Role 单个角色的权限
+func (r *RBAC) Role(ctx context.Context, role string) ([]string, error) {
+	val, err := r.cmd.HGet(ctx, rolesKey, role).Result()
+	switch {
+	case errors.Is(err, redis.Nil):
+		return nil, ErrRoleNotFound
+	case err != nil:
+		return nil, err
+	}
+	var perms []string
+	err = json.Unmarshal([]byte(val), &perms)
+	return perms, err
+}
+
+// SaveRole 创建角色或者覆盖角色的权限
+func (r *RBAC) SaveRole(ctx context.Context, role string, perms []string) error {
+	if err := checkRole(role, perms); err != nil {
+		return err
+	}
+	val, _ := json.Marshal(perms)
+	return r.cmd.HSet(ctx, rolesKey, role, val).Err()
+}
+
+// DeleteRole 删除角色
+func (r *RBAC) DeleteRole(ctx context.Context, role string) error {
+	n, err := r.cmd.HDel(ctx, rolesKey, role).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrRoleNotFound
+	}
+	return nil
+}
+
+// UserRoles 学号被分配的角色
+func (r *RBAC) UserRoles(ctx context.Context, studentId string) ([]string, error) {
+	val, err := r.cmd.HGet(ctx, assignmentsKey, studentId).Result()
+	switch {
+	case errors.Is(err, redis.Nil):
+		return []string{}, nil
+	case err != nil:
+		return nil, err
+	}
+	var roles []string
+	err = json.Unmarshal([]byte(val), &roles)
+	return roles, err
+}
+
+// SetUserRoles 覆盖学号的角色,传空表示收回全部角色
+func (r *RBAC) SetUserRoles(ctx context.Context, studentId string, roles []string) error {
+	if len(roles) == 0 {
+		return r.cmd.HDel(ctx, assignmentsKey, studentId).Err()
+	}
+	exist, err := r.cmd.HMGet(ctx, rolesKey, roles...).Result()
+	if err != nil {
+		return err
+	}
+	for i, val := range exist {
+		if val == nil {
+			return fmt.Errorf("%w: %s", ErrRoleNotFound, roles[i])
+		}
+	}
+	roles = dedup(roles)
+	val, _ := json.Marshal(roles)
+	return r.cmd.HSet(ctx, assignmentsKey, studentId, val).Err()
+}
+
+func checkRole(role string, perms []string) error {
+	if !perm.ValidRole(role) {
+		return fmt.Errorf("%w: %q", ErrInvalidRoleName, role)
+	}
+	for _, p := range perms {
+		if !perm.Known(p) {
+			return fmt.Errorf("%w: %q", ErrUnknownPermission, p)
+		}
+	}
+	return nil
+}
+
+func dedup(s []string) []string {
+	sort.Strings(s)
+	res := s[:0]
+	for i, v := range s {
+		if i == 0 || v != s[i-1] {
+			res = append(res, v)
+		}
+	}
+	return res
+}
@@ -2,36 +2,47 @@ package static
 
 import (
 	"errors"
-	"fmt"
 	staticv1 "github.com/asynccnu/be-api/gen/proto/static/v1"
 	"github.com/asynccnu/bff/errs"
+	"github.com/asynccnu/bff/pkg/auditx"
 	"github.com/asynccnu/bff/pkg/ginx"
 	"github.com/asynccnu/bff/pkg/htmlx"
 	"github.com/asynccnu/bff/web"
 	"github.com/asynccnu/bff/web/ijwt"
+	"github.com/asynccnu/bff/web/rbac/perm"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/copier"
+	"net/http"
 )
 
 type StaticHandler struct {
 	staticClient           staticv1.StaticServiceClient
 	fileToHTMLConverterMap map[string]htmlx.FileToHTMLConverter
-	Administrators         map[string]struct{}
+	Authorizer             web.Authorizer
 }
 
 func NewStaticHandler(
 	staticClient staticv1.StaticServiceClient,
 	fileToHTMLConverterMap map[string]htmlx.FileToHTMLConverter,
-	administrators map[string]struct{},
+	authorizer web.Authorizer,
 ) *StaticHandler {
-	return &StaticHandler{staticClient: staticClient, fileToHTMLConverterMap: fileToHTMLConverterMap, Administrators: administrators}
+	return &StaticHandler{staticClient: staticClient, fileToHTMLConverterMap: fileToHTMLConverterMap, Authorizer: authorizer}
 }
 
 func (h *StaticHandler) RegisterRoutes(s *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
 	sg := s.Group("/statics")
-	sg.GET("", ginx.WrapReq(h.GetStaticByName))
-	sg.GET("/match/labels", ginx.Wrap(h.GetStaticByLabels))
-	sg.POST("/save", authMiddleware, ginx.WrapClaimsAndReq(h.SaveStatic))
+	sg.GET("", authMiddleware, ginx.WrapReq(h.GetStaticByName))
+	sg.GET("/match/labels", authMiddleware, ginx.Wrap(h.GetStaticByLabels))
+	sg.POST("/save", authMiddleware, h.Authorizer.RequirePermission(perm.StaticWrite), ginx.WrapClaimsAndReq(h.SaveStatic))
+}
+
+// AuthPolicies 静态资源的读取接口游客和模拟登录都能用,保存仍然要登录,也可以用 API Key 调用
+func (h *StaticHandler) AuthPolicies() []web.RoutePolicy {
+	return []web.RoutePolicy{
+		{Method: http.MethodGet, Path: "/statics", Auth: web.AuthOptional, Impersonable: true},
+		{Method: http.MethodGet, Path: "/statics/match/*", Auth: web.AuthOptional, Impersonable: true},
+		{Method: http.MethodPost, Path: "/statics/save", Auth: web.AuthRequired, Service: true},
+	}
 }
 
 // @Summary 获取静态资源[精确名称]
@@ -74,13 +85,17 @@ func (h *StaticHandler) GetStaticByName(ctx *gin.Context, req GetStaticByNameReq
 // @Success 200 {object} web.Response "成功"
 // @Router /statics/save [post]
 func (h *StaticHandler) SaveStatic(ctx *gin.Context, req SaveStaticReq, uc ijwt.UserClaims) (web.Response, error) {
-	// 管理员身份验证
-	if !h.isAdmin(uc.StudentId) {
-		return web.Response{}, errs.SAVE_STATIC_ERROR(fmt.Errorf("没有访问权限: %s", uc.StudentId))
-	}
 	if req.Name == "" {
 		return web.Response{}, errs.INVALID_PARAM_VALUE_ERROR(errors.New("静态名称不合法"))
 	}
+	// 覆盖之前的内容记进审计日志,第一次保存的时候查不到
+	if old, err := h.staticClient.GetStaticByName(ctx, &staticv1.GetStaticByNameRequest{Name: req.Name}); err == nil {
+		auditx.SetBefore(ctx, SaveStaticReq{
+			Name:    old.GetStatic().GetName(),
+			Content: old.GetStatic().GetContent(),
+			Labels:  old.GetStatic().GetLabels(),
+		})
+	}
 	_, err := h.staticClient.SaveStatic(ctx, &staticv1.SaveStaticRequest{
 		Static: &staticv1.Static{
 			Name:    req.Name,
@@ -99,11 +114,6 @@ func (h *StaticHandler) SaveStatic(ctx *gin.Context, req SaveStaticReq, uc ijwt.
 	}, nil
 }
 
-func (h *StaticHandler) isAdmin(studentId string) bool {
-	_, exists := h.Administrators[studentId]
-	return exists
-}
-
 // @Summary 获取静态资源[标签匹配]
 // @Description 根据静labels匹配合适的静态资源
 // @Tags 静态
@@ -11,3 +11,36 @@ type Response struct {
 	Code int         `json:"code"`
 	Data interface{} `json:"data"`
 }
+
+// Authorizer 权限校验,RequirePermission 返回的中间件必须挂在登录中间件后面
+type Authorizer interface {
+	RequirePermission(perm string) gin.HandlerFunc
+}
+
+// AuthPolicy 路由对登录的要求
+type AuthPolicy string
+
+const (
+	AuthRequired AuthPolicy = "required" // 必须登录,没有声明的路由都是这个
+	AuthOptional AuthPolicy = "optional" // 带了有效的 token 就解析出用户信息,否则按游客处理,学号为空
+	AuthPublic   AuthPolicy = "public"   // 不检查 token,这类路由不挂 authMiddleware
+)
+
+// RoutePolicy 声明一类路由的登录要求
+type RoutePolicy struct {
+	Method string // 为空表示所有方法
+	Path   string // 相对 /api/v1 的路由,和注册时的写法一致(包括 :param),支持 path.Match 的通配符
+	Auth   AuthPolicy
+	// Impersonable 管理员模拟学生登录时能不能访问,只给没有副作用的查询接口打开。
+	// 不按请求方法判断,有的查询用的是 POST,有的 GET 会顺手写数据
+	Impersonable bool
+	// Service 能不能用 API Key 访问,只给挂了 RequirePermission 的管理接口打开,能调哪些再由 key 的 scopes 决定
+	Service bool
+}
+
+// AuthPolicies 有游客、模拟登录或者 API Key 可以访问的路由的 handler 实现这个接口,和 RegisterRoutes 写在一起。
+// AuthOptional 和其它需要登录的路由要挂上 authMiddleware,由它按声明决定怎么处理;
+// AuthPublic 的路由不挂,声明只是为了让管理接口的路由表如实显示,路由表只看这些声明,不看挂了哪些中间件
+type AuthPolicies interface {
+	AuthPolicies() []RoutePolicy
+}
@@ -0,0 +1,64 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	ErrInvalidNickname = errors.New("非法的昵称")
+	ErrInvalidAvatar   = errors.New("非法的头像")
+)
+
+// maxNicknameLen 昵称最多多少个字,按字符算,不是字节
+const maxNicknameLen = 20
+
+// reservedNicknames 容易被误认为官方账号的昵称,不区分大小写,包含就不行
+var reservedNicknames = []string{"管理员", "官方", "客服", "木犀", "华师匣子", "admin", "muxi", "ccnubox"}
+
+// checkNickname 昵称不能为空,不能有首尾空格、换行和零宽字符之类看不见的内容
+func checkNickname(nickname string) error {
+	if nickname == "" || strings.TrimSpace(nickname) != nickname {
+		return fmt.Errorf("%w: 不能为空,首尾不能有空格", ErrInvalidNickname)
+	}
+	if !utf8.ValidString(nickname) || utf8.RuneCountInString(nickname) > maxNicknameLen {
+		return fmt.Errorf("%w: 最多 %d 个字", ErrInvalidNickname, maxNicknameLen)
+	}
+	for _, r := range nickname {
+		if !unicode.IsPrint(r) || unicode.Is(unicode.Cf, r) {
+			return fmt.Errorf("%w: 包含不可见字符", ErrInvalidNickname)
+		}
+	}
+	lower := strings.ToLower(nickname)
+	for _, w := range reservedNicknames {
+		if strings.Contains(lower, w) {
+			return fmt.Errorf("%w: 不能包含 %q", ErrInvalidNickname, w)
+		}
+	}
+	return nil
+}
+
+// checkAvatar 头像只能是图床 CDN 上的图片,不能随便引用外部地址,为空表示使用默认头像
+func checkAvatar(avatar string, host string) error {
+	if avatar == "" {
+		return nil
+	}
+	u, err := url.Parse(avatar)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.User != nil || !strings.EqualFold(u.Host, host) {
+		return fmt.Errorf("%w: 必须是 %s 上的图片", ErrInvalidAvatar, host)
+	}
+	return nil
+}
+
+// cdnHost 配置里的 CDN 域名可能带着协议和路径
+func cdnHost(domainName string) string {
+	if u, err := url.Parse(domainName); err == nil && u.Host != "" {
+		return u.Host
+	}
+	host, _, _ := strings.Cut(domainName, "/")
+	return host
+}
@@ -0,0 +1,57 @@
+package user
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckNickname(t *testing.T) {
+	tests := []struct {
+		nickname string
+		wantErr  bool
+	}{
+		{nickname: "小明"},
+		{nickname: "Tom 2023"},
+		{nickname: "", wantErr: true},
+		{nickname: " 小明", wantErr: true},
+		{nickname: "小​明", wantErr: true},
+		{nickname: "小\n明", wantErr: true},
+		{nickname: "木犀官方小助手", wantErr: true},
+		{nickname: "ADMIN", wantErr: true},
+		{nickname: "一二三四五六七八九十一二三四五六七八九十", wantErr: false},
+		{nickname: "一二三四五六七八九十一二三四五六七八九十一", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.nickname, func(t *testing.T) {
+			err := checkNickname(tt.nickname)
+			if (err != nil) != tt.wantErr || (err != nil && !errors.Is(err, ErrInvalidNickname)) {
+				t.Errorf("checkNickname(%q) = %v, wantErr %v", tt.nickname, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCheckAvatar(t *testing.T) {
+	host := cdnHost("https://cdn.example.com/")
+	tests := []struct {
+		avatar  string
+		wantErr bool
+	}{
+		{avatar: ""},
+		{avatar: "https://cdn.example.com/avatar/1.png"},
+		{avatar: "http://CDN.example.com/avatar/1.png"},
+		{avatar: "https://evil.com/avatar/1.png", wantErr: true},
+		{avatar: "https://cdn.example.com@evil.com/1.png", wantErr: true},
+		{avatar: "https://user@cdn.example.com/1.png", wantErr: true},
+		{avatar: "javascript://cdn.example.com/%0aalert(1)", wantErr: true},
+		{avatar: "/avatar/1.png", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.avatar, func(t *testing.T) {
+			err := checkAvatar(tt.avatar, host)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkAvatar(%q) = %v, wantErr %v", tt.avatar, err, tt.wantErr)
+			}
+		})
+	}
+}
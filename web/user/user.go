@@ -1,28 +1,45 @@
 package user
 
 import (
+	"errors"
+	"fmt"
 	ccnuv1 "github.com/asynccnu/be-api/gen/proto/ccnu/v1"
 	userv1 "github.com/asynccnu/be-api/gen/proto/user/v1"
 	"github.com/asynccnu/bff/errs"
 	"github.com/asynccnu/bff/pkg/ginx"
 	"github.com/asynccnu/bff/web"
 	"github.com/asynccnu/bff/web/ijwt"
+	"github.com/asynccnu/bff/web/loginguard"
+	"github.com/asynccnu/bff/web/rbac"
+	"github.com/asynccnu/bff/web/rbac/perm"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"math"
+	"net/http"
+	"strconv"
 )
 
 // user板块的控制路由
 type UserHandler struct {
 	ijwt.Handler
-	userSvc userv1.UserServiceClient
-	ccnuSvc ccnuv1.CCNUServiceClient
+	guard      *loginguard.LoginGuard
+	rbac       *rbac.RBAC
+	avatarHost string // 头像只能用图床 CDN 上的图片
+	Authorizer web.Authorizer
+	userSvc    userv1.UserServiceClient
+	ccnuSvc    ccnuv1.CCNUServiceClient
 }
 
-func NewUserHandler(hdl ijwt.Handler, userSvc userv1.UserServiceClient, ccnuSvc ccnuv1.CCNUServiceClient) *UserHandler {
+func NewUserHandler(hdl ijwt.Handler, guard *loginguard.LoginGuard, r *rbac.RBAC, cdnDomain string,
+	authorizer web.Authorizer, userSvc userv1.UserServiceClient, ccnuSvc ccnuv1.CCNUServiceClient) *UserHandler {
 	return &UserHandler{
-		Handler: hdl,
-		userSvc: userSvc,
-		ccnuSvc: ccnuSvc,
+		Handler:    hdl,
+		guard:      guard,
+		rbac:       r,
+		avatarHost: cdnHost(cdnDomain),
+		Authorizer: authorizer,
+		userSvc:    userSvc,
+		ccnuSvc:    ccnuSvc,
 	}
 }
 
@@ -32,10 +49,36 @@ func (h *UserHandler) RegisterRoutes(s *gin.RouterGroup, authMiddleware gin.Hand
 	ug.POST("/login_ccnu", ginx.WrapReq(h.LoginByCCNU))
 	ug.POST("/logout", authMiddleware, ginx.Wrap(h.Logout))
 	ug.GET("/refresh_token", ginx.Wrap(h.RefreshToken))
+	ug.GET("/me", authMiddleware, ginx.WrapClaims(h.Me))
+	ug.GET("/profile", authMiddleware, ginx.WrapClaims(h.GetProfile))
+	ug.PUT("/profile", authMiddleware, ginx.WrapClaimsAndReq(h.EditProfile))
+	ug.GET("/:id/public", ginx.Wrap(h.GetPublicProfile))
+
+	ug.GET("/sessions", authMiddleware, ginx.WrapClaims(h.GetSessions))
+	ug.DELETE("/sessions/:ssid", authMiddleware, ginx.WrapClaims(h.DeleteSession))
+	ug.POST("/sessions/logout_others", authMiddleware, ginx.WrapClaims(h.LogoutOthers))
+
+	ag := s.Group("/admin/users", authMiddleware)
+	ag.POST("/:studentId/impersonate", h.Authorizer.RequirePermission(perm.UserImpersonate), ginx.WrapClaims(h.ImpersonateUser))
+}
+
+// AuthPolicies 登录和刷新的时候还没有有效的短token,别人的公开资料游客也能看。
+// 模拟登录可以查看身份、个人资料和设备列表
+func (h *UserHandler) AuthPolicies() []web.RoutePolicy {
+	return []web.RoutePolicy{
+		{Method: http.MethodPost, Path: "/users/login_ccnu", Auth: web.AuthPublic},
+		{Method: http.MethodGet, Path: "/users/refresh_token", Auth: web.AuthPublic},
+		{Method: http.MethodGet, Path: "/users/:id/public", Auth: web.AuthPublic},
+		{Method: http.MethodGet, Path: "/users/me", Auth: web.AuthRequired, Impersonable: true},
+		{Method: http.MethodGet, Path: "/users/profile", Auth: web.AuthRequired, Impersonable: true},
+		{Method: http.MethodGet, Path: "/users/sessions", Auth: web.AuthRequired, Impersonable: true},
+	}
 }
 
 // @Summary ccnu登录
-// @Description 通过学号和密码进行登录认证
+// @Description 通过学号和密码进行登录认证,同一学号或 IP 尝试过于频繁、或者连续输错密码被锁定时返回 429,
+// @Description 响应头 Retry-After 是需要等待的秒数
+// @Description 网页端可以带上 X-Auth-Mode: cookie(或者使用 jwt.cookie.clientTypes 中的 X-Client-Type)开启 cookie 模式,token 写进 HttpOnly 的 cookie,不再放在响应头里
 // @Tags 用户
 // @Accept json
 // @Produce json
@@ -43,6 +86,11 @@ func (h *UserHandler) RegisterRoutes(s *gin.RouterGroup, authMiddleware gin.Hand
 // @Success 200 {object} web.Response "Success"
 // @Router /users/login_ccnu [post]
 func (h *UserHandler) LoginByCCNU(ctx *gin.Context, req LoginByCCNUReq) (web.Response, error) {
+	if retryAfter := h.guard.Allow(ctx, req.StudentId, ctx.ClientIP()); retryAfter > 0 {
+		ctx.Header("Retry-After", strconv.FormatInt(int64(math.Ceil(retryAfter.Seconds())), 10))
+		return web.Response{}, errs.LOGIN_LOCKED_ERROR(retryAfter, errors.New("登录尝试被拦截: "+req.StudentId))
+	}
+
 	//请求部分,内层调用的be-api上面的东西,实现grpc通信可以直接点进去看
 	_, err := h.ccnuSvc.Login(ctx, &ccnuv1.LoginRequest{
 		StudentId: req.StudentId,
@@ -50,8 +98,9 @@ func (h *UserHandler) LoginByCCNU(ctx *gin.Context, req LoginByCCNUReq) (web.Res
 	})
 	switch {
 	case err == nil:
-	// 直接向下执行
+		h.guard.Succeed(ctx, req.StudentId)
 	case ccnuv1.IsInvalidSidOrPwd(err):
+		h.guard.Fail(ctx, req.StudentId, ctx.ClientIP())
 		return web.Response{}, errs.USER_SID_Or_PASSPORD_ERROR(err)
 	default:
 		return web.Response{}, errs.LOGIN_BY_CCNU_ERROR(err)
@@ -63,7 +112,11 @@ func (h *UserHandler) LoginByCCNU(ctx *gin.Context, req LoginByCCNUReq) (web.Res
 	}
 
 	err = h.SetLoginToken(ctx, req.StudentId, req.Password)
-	if err != nil {
+	switch {
+	case err == nil:
+	case errors.Is(err, ijwt.ErrDeviceBinding):
+		return web.Response{}, errs.INVALID_PARAM_VALUE_ERROR(err)
+	default:
 		return web.Response{}, errs.JWT_SYSTEM_ERROR(err)
 	}
 	return web.Response{
@@ -88,8 +141,11 @@ func (h *UserHandler) Logout(ctx *gin.Context) (web.Response, error) {
 	}, nil
 }
 
-// @Summary 刷新短token
-// @Description 通过长token刷新短token
+// @Summary 刷新token
+// @Description 通过长token刷新,同时返回新的短token(x-jwt-token)和新的长token(x-refresh-token),旧的长token 10 秒后失效,
+// @Description 这期间重复刷新(多个标签页同时刷新、响应丢失后重试)拿到的是同一个新的长token;
+// @Description 已经失效的长token如果再次被使用,会被当作泄露处理,整个会话都会被注销
+// @Description cookie 模式下长短token都通过 cookie 收发,请求头需要带上 X-CSRF-Token
 // @Tags 用户
 // @Accept json
 // @Produce json
@@ -97,12 +153,13 @@ func (h *UserHandler) Logout(ctx *gin.Context) (web.Response, error) {
 // @Success 200 {object} web.Response "Success"
 // @Router /users/refresh_token [get]
 func (h *UserHandler) RefreshToken(ctx *gin.Context) (web.Response, error) {
-	tokenStr := h.ExtractToken(ctx)
+	// cookie 模式下刷新令牌由浏览器自动带上,虽然是 GET 也会换发 token,同样要校验 csrf
+	if err := h.CheckCSRF(ctx); err != nil {
+		return web.Response{}, errs.CSRF_ERROR(err)
+	}
+	tokenStr := h.ExtractRefreshToken(ctx)
 	rc := &ijwt.RefreshClaims{}
-	token, err := jwt.ParseWithClaims(tokenStr, rc, func(*jwt.Token) (interface{}, error) {
-		// 可以根据具体情况给出不同的key
-		return h.RCJWTKey(), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenStr, rc, h.RefreshKeyfunc())
 	if err != nil {
 		return web.Response{}, errs.AUTH_PASSED_ERROR(err)
 	}
@@ -113,17 +170,282 @@ func (h *UserHandler) RefreshToken(ctx *gin.Context) (web.Response, error) {
 	if err != nil || ok {
 		return web.Response{}, errs.JWT_SYSTEM_ERROR(err)
 	}
+	//旧版本的刷新令牌里带着密码,迁移到服务端之后新签发的token里就不再有了
+	err = h.MigrateCredential(ctx, rc.Ssid, ijwt.Credential{StudentId: rc.StudentId, Password: rc.Password})
+	if err != nil {
+		return web.Response{}, errs.JWT_SYSTEM_ERROR(err)
+	}
 	//这里设置到相应头里了(非常神秘的模式),这里的jwt参数居然直接被耦合到服务里面去了
-	err = h.SetJWTToken(ctx, ijwt.ClaimParams{
-		StudentId: rc.StudentId,
-		Password:  rc.Password,
-		Ssid:      rc.Ssid,
-		UserAgent: rc.UserAgent,
+	err = h.RotateRefreshToken(ctx, *rc)
+	switch {
+	case err == nil:
+	case errors.Is(err, ijwt.ErrRefreshTokenReused), errors.Is(err, ijwt.ErrRefreshTokenInvalid),
+		errors.Is(err, ijwt.ErrDeviceBinding):
+		return web.Response{}, errs.UNAUTHORIED_ERROR(err)
+	default:
+		return web.Response{}, errs.JWT_SYSTEM_ERROR(err)
+	}
+	return web.Response{
+		Msg: "Success",
+	}, nil
+}
+
+// @Summary 获取当前用户
+// @Description 返回当前登录的学号、会话、长短token的过期时间、角色和权限以及登录的设备,客户端不需要再自己解析token
+// @Tags 用户
+// @Produce json
+// @Success 200 {object} web.Response{data=MeVo} "Success"
+// @Router /users/me [get]
+func (h *UserHandler) Me(ctx *gin.Context, uc ijwt.UserClaims) (web.Response, error) {
+	me := MeVo{StudentId: uc.StudentId, Ssid: uc.Ssid, ImpersonatedBy: uc.ImpersonatedBy}
+	if uc.ExpiresAt != nil {
+		me.AccessExpiresAt = uc.ExpiresAt.UnixMilli()
+	}
+	// API Key 没有会话,也不参与 RBAC
+	if uc.IsService() {
+		me.Roles, me.Permissions = []string{}, uc.Scopes
+		return web.Response{
+			Msg:  "Success",
+			Data: me,
+		}, nil
+	}
+
+	var err error
+	me.Roles, me.Permissions, err = h.rbac.UserPermissions(ctx, uc.StudentId)
+	if err != nil {
+		return web.Response{}, errs.GET_CURRENT_USER_ERROR(err)
+	}
+	me.Device = DeviceVo{
+		ClientType: uc.ClientType,
+		DeviceId:   uc.DeviceId,
+		UserAgent:  uc.UserAgent,
+		Signed:     uc.DeviceKey != "",
+	}
+	sessions, err := h.Sessions(ctx, uc.StudentId)
+	if err != nil {
+		return web.Response{}, errs.GET_CURRENT_USER_ERROR(err)
+	}
+	// 会话列表上线之前登录的会话查不到,只返回 token 里有的信息
+	for _, s := range sessions {
+		if s.Ssid != uc.Ssid {
+			continue
+		}
+		me.RefreshExpiresAt = s.ExpiresAt.UnixMilli()
+		me.Device.DeviceName = s.DeviceName
+		me.Device.UserAgent = s.UserAgent
+		me.Device.IP = s.IP
+		me.Device.LoginTime = s.LoginTime.UnixMilli()
+	}
+	return web.Response{
+		Msg:  "Success",
+		Data: me,
+	}, nil
+}
+
+// @Summary 获取登录设备列表
+// @Description 获取当前账号所有登录中的设备,按最后活跃时间倒序,登录时可以通过 X-Device-Name 请求头上报设备名
+// @Tags 用户
+// @Produce json
+// @Success 200 {object} web.Response{data=GetSessionsResp} "Success"
+// @Router /users/sessions [get]
+func (h *UserHandler) GetSessions(ctx *gin.Context, uc ijwt.UserClaims) (web.Response, error) {
+	sessions, err := h.Sessions(ctx, uc.StudentId)
+	if err != nil {
+		return web.Response{}, errs.GET_SESSIONS_ERROR(err)
+	}
+	resp := GetSessionsResp{Sessions: make([]SessionVo, 0, len(sessions))}
+	for _, s := range sessions {
+		resp.Sessions = append(resp.Sessions, SessionVo{
+			Ssid:       s.Ssid,
+			DeviceName: s.DeviceName,
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			LoginTime:  s.LoginTime.UnixMilli(),
+			LastSeen:   s.LastSeen.UnixMilli(),
+			Current:    s.Ssid == uc.Ssid,
+
+			ImpersonatedBy: s.ImpersonatedBy,
+		})
+	}
+	return web.Response{
+		Msg:  "Success",
+		Data: resp,
+	}, nil
+}
+
+// @Summary 注销某台登录设备
+// @Description 让指定的设备下线,注销当前设备等同于登出
+// @Tags 用户
+// @Produce json
+// @Param ssid path string true "设备的ssid,从设备列表中获取"
+// @Success 200 {object} web.Response "Success"
+// @Router /users/sessions/{ssid} [delete]
+func (h *UserHandler) DeleteSession(ctx *gin.Context, uc ijwt.UserClaims) (web.Response, error) {
+	err := h.RevokeSession(ctx, uc.StudentId, ctx.Param("ssid"))
+	switch {
+	case err == nil:
+	case errors.Is(err, ijwt.ErrSessionNotFound):
+		return web.Response{}, errs.SESSION_NOT_FOUND_ERROR(err)
+	default:
+		return web.Response{}, errs.REVOKE_SESSION_ERROR(err)
+	}
+	return web.Response{
+		Msg: "Success",
+	}, nil
+}
+
+// @Summary 退出其他所有设备
+// @Description 除了当前设备以外,其它登录中的设备全部下线
+// @Tags 用户
+// @Produce json
+// @Success 200 {object} web.Response "Success"
+// @Router /users/sessions/logout_others [post]
+func (h *UserHandler) LogoutOthers(ctx *gin.Context, uc ijwt.UserClaims) (web.Response, error) {
+	err := h.RevokeOtherSessions(ctx, uc.StudentId, uc.Ssid)
+	if err != nil {
+		return web.Response{}, errs.REVOKE_SESSION_ERROR(err)
+	}
+	return web.Response{
+		Msg: "Success",
+	}, nil
+}
+
+// @Summary 获取个人资料
+// @Description 获取自己的头像、昵称、称号等信息,new 为 true 表示还没有编辑过,这时除了学号都是空的
+// @Tags 用户
+// @Produce json
+// @Success 200 {object} web.Response{data=UserProfileVo} "Success"
+// @Router /users/profile [get]
+func (h *UserHandler) GetProfile(ctx *gin.Context, uc ijwt.UserClaims) (web.Response, error) {
+	res, err := h.userSvc.GetUserProfile(ctx, &userv1.GetUserProfileReq{StudentId: uc.StudentId})
+	switch {
+	case err == nil:
+	case userv1.IsUserNotFound(err):
+		// 还没有编辑过就没有资料,第一次保存的时候由 user 服务创建,这里不写任何东西
+		return web.Response{
+			Msg:  "Success",
+			Data: UserProfileVo{StudentId: uc.StudentId, New: true, TitleOwnership: map[string]bool{}},
+		}, nil
+	default:
+		return web.Response{}, errs.GET_PROFILE_ERROR(err)
+	}
+	return web.Response{
+		Msg:  "Success",
+		Data: toProfileVo(res.GetProfile()),
+	}, nil
+}
+
+// @Summary 修改个人资料
+// @Description 修改头像、昵称和正在使用的称号。昵称最多 20 个字,不能包含不可见字符或者冒充官方;
+// @Description 头像必须是通过图床(/tube/access_token)上传到 CDN 上的图片,为空表示使用默认头像;称号只能选已经获得的,为空表示不使用
+// @Tags 用户
+// @Accept json
+// @Produce json
+// @Param request body UserEditReq true "个人资料"
+// @Success 200 {object} web.Response{data=UserProfileVo} "Success"
+// @Router /users/profile [put]
+func (h *UserHandler) EditProfile(ctx *gin.Context, req UserEditReq, uc ijwt.UserClaims) (web.Response, error) {
+	if err := checkNickname(req.Nickname); err != nil {
+		return web.Response{}, errs.INVALID_PARAM_VALUE_ERROR(err)
+	}
+	if err := checkAvatar(req.Avatar, h.avatarHost); err != nil {
+		return web.Response{}, errs.INVALID_PARAM_VALUE_ERROR(err)
+	}
+	// 有没有这个称号由 user 服务在保存的时候一起校验,BFF 先读再写的话称号可能刚好被收回
+	res, err := h.userSvc.UpdateUserProfile(ctx, &userv1.UpdateUserProfileReq{
+		StudentId:  uc.StudentId,
+		Avatar:     req.Avatar,
+		Nickname:   req.Nickname,
+		UsingTitle: req.UsingTitle,
 	})
+	switch {
+	case err == nil:
+	case userv1.IsTitleNotOwned(err):
+		return web.Response{}, errs.INVALID_PARAM_VALUE_ERROR(fmt.Errorf("还没有获得称号 %q: %w", req.UsingTitle, err))
+	default:
+		return web.Response{}, errs.EDIT_PROFILE_ERROR(err)
+	}
+	return web.Response{
+		Msg:  "Success",
+		Data: toProfileVo(res.GetProfile()),
+	}, nil
+}
+
+// @Summary 获取别人的公开资料
+// @Description 只包含头像和昵称,id 是个人资料中的 id,不是学号
+// @Tags 用户
+// @Produce json
+// @Param id path int true "用户 id"
+// @Success 200 {object} web.Response{data=UserPublicProfileVo} "Success"
+// @Router /users/{id}/public [get]
+func (h *UserHandler) GetPublicProfile(ctx *gin.Context) (web.Response, error) {
+	id, err := strconv.ParseInt(ctx.Param("id"), 10, 64)
 	if err != nil {
-		return web.Response{}, errs.JWT_SYSTEM_ERROR(err)
+		return web.Response{}, errs.INVALID_PARAM_VALUE_ERROR(err)
 	}
+	res, err := h.userSvc.GetPublicProfile(ctx, &userv1.GetPublicProfileReq{Id: id})
+	switch {
+	case err == nil:
+	case userv1.IsUserNotFound(err):
+		return web.Response{}, errs.PROFILE_NOT_FOUND_ERROR(err)
+	default:
+		return web.Response{}, errs.GET_PROFILE_ERROR(err)
+	}
+	p := res.GetProfile()
 	return web.Response{
 		Msg: "Success",
+		Data: UserPublicProfileVo{
+			Id:       p.GetId(),
+			Avatar:   p.GetAvatar(),
+			Nickname: p.GetNickname(),
+		},
 	}, nil
 }
+
+// @Summary 模拟学生登录
+// @Description 管理员以学生的身份查看数据,用来复现学生反馈的课表、成绩等问题,需要 user:impersonate 权限。
+// @Description 返回的 token 有效期 15 分钟,没有刷新令牌,只能访问各个 handler 在 AuthPolicies 里声明了 Impersonable 的查询接口,不能访问管理接口,期间的每个请求都会记审计日志。
+// @Description 这次模拟会出现在学生的登录设备列表里,学生可以自己注销
+// @Tags 用户
+// @Produce json
+// @Param studentId path string true "学号"
+// @Success 200 {object} web.Response{data=ImpersonateResp} "Success"
+// @Router /admin/users/{studentId}/impersonate [post]
+func (h *UserHandler) ImpersonateUser(ctx *gin.Context, uc ijwt.UserClaims) (web.Response, error) {
+	studentId := ctx.Param("studentId")
+	token, expiresAt, err := h.Handler.Impersonate(ctx, uc, studentId)
+	switch {
+	case err == nil:
+	case errors.Is(err, ijwt.ErrImpersonation):
+		return web.Response{}, errs.ROLE_ERROR(err)
+	default:
+		return web.Response{}, errs.IMPERSONATE_ERROR(err)
+	}
+	return web.Response{
+		Msg: "Success",
+		Data: ImpersonateResp{
+			StudentId: studentId,
+			Token:     token,
+			ExpiresAt: expiresAt.UnixMilli(),
+		},
+	}, nil
+}
+
+func toProfileVo(p *userv1.UserProfile) UserProfileVo {
+	vo := UserProfileVo{
+		Id:                   p.GetId(),
+		StudentId:            p.GetStudentId(),
+		Avatar:               p.GetAvatar(),
+		Nickname:             p.GetNickname(),
+		New:                  p.GetUtime() == 0,
+		GradeSharingIsSigned: p.GetGradeSharingIsSigned(),
+		UsingTitle:           p.GetUsingTitle(),
+		TitleOwnership:       make(map[string]bool, len(p.GetTitles())),
+		Utime:                p.GetUtime(),
+		Ctime:                p.GetCtime(),
+	}
+	for _, t := range p.GetTitles() {
+		vo.TitleOwnership[t] = true
+	}
+	return vo
+}
@@ -5,12 +5,57 @@ type LoginByCCNUReq struct {
 	Password  string `json:"password"` // 密码
 }
 
+type SessionVo struct {
+	Ssid       string `json:"ssid"`
+	DeviceName string `json:"device_name"`
+	UserAgent  string `json:"user_agent"`
+	IP         string `json:"ip"`
+	LoginTime  int64  `json:"login_time"` // 毫秒时间戳
+	LastSeen   int64  `json:"last_seen"`  // 毫秒时间戳
+	Current    bool   `json:"current"`    // 是否是发起请求的这台设备
+	// 不为空表示是这个管理员的模拟登录,不是本人的设备
+	ImpersonatedBy string `json:"impersonated_by,omitempty"`
+}
+
+type GetSessionsResp struct {
+	Sessions []SessionVo `json:"sessions"`
+}
+
+// MeVo 当前登录的身份,不包含任何密码和 token
+type MeVo struct {
+	StudentId        string   `json:"student_id"` // API Key 是 apikey:<id>
+	Ssid             string   `json:"ssid"`
+	AccessExpiresAt  int64    `json:"access_expires_at"`  // 短token的过期时间,毫秒时间戳
+	RefreshExpiresAt int64    `json:"refresh_expires_at"` // 长token的过期时间,毫秒时间戳,刷新不会延长
+	Roles            []string `json:"roles"`
+	Permissions      []string `json:"permissions"` // "*" 表示全部权限,API Key 是创建时指定的 scopes
+	Device           DeviceVo `json:"device"`
+	ImpersonatedBy   string   `json:"impersonated_by,omitempty"` // 模拟登录时是管理员的学号,客户端据此显示提示条
+}
+
+type DeviceVo struct {
+	ClientType string `json:"client_type"`
+	DeviceId   string `json:"device_id"`
+	DeviceName string `json:"device_name"`
+	UserAgent  string `json:"user_agent"`
+	IP         string `json:"ip"`         // 登录时的 IP
+	LoginTime  int64  `json:"login_time"` // 毫秒时间戳
+	Signed     bool   `json:"signed"`     // 是否绑定了设备公钥,绑定之后每个请求都要签名
+}
+
 type UserEditReq struct {
 	Avatar     string `json:"avatar"`
 	Nickname   string `json:"nickname"`
 	UsingTitle string `json:"using_title"`
 }
 
+// ImpersonateResp 只有短token,放在 Authorization 里使用,过期之后需要重新发起
+type ImpersonateResp struct {
+	StudentId string `json:"student_id"`
+	Token     string `json:"token"`
+	ExpiresAt int64  `json:"expires_at"` // 毫秒时间戳
+}
+
 // UserProfileVo 自己的信息
 type UserProfileVo struct {
 	Id                   int64           `json:"id"`
@@ -1,32 +1,42 @@
 package website
 
 import (
-	"fmt"
 	websitev1 "github.com/asynccnu/be-api/gen/proto/website/v1"
 	"github.com/asynccnu/bff/errs"
 	"github.com/asynccnu/bff/pkg/ginx"
 	"github.com/asynccnu/bff/web"
 	"github.com/asynccnu/bff/web/department"
 	"github.com/asynccnu/bff/web/ijwt"
+	"github.com/asynccnu/bff/web/rbac/perm"
 	"github.com/gin-gonic/gin"
 	"github.com/jinzhu/copier"
+	"net/http"
 )
 
 type WebsiteHandler struct {
-	websiteClient  websitev1.WebsiteServiceClient
-	Administrators map[string]struct{}
+	websiteClient websitev1.WebsiteServiceClient
+	Authorizer    web.Authorizer
 }
 
 func NewWebsiteHandler(websiteClient websitev1.WebsiteServiceClient,
-	administrators map[string]struct{}) *WebsiteHandler {
-	return &WebsiteHandler{websiteClient: websiteClient, Administrators: administrators}
+	authorizer web.Authorizer) *WebsiteHandler {
+	return &WebsiteHandler{websiteClient: websiteClient, Authorizer: authorizer}
 }
 
 func (h *WebsiteHandler) RegisterRoutes(s *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
 	sg := s.Group("/website")
 	sg.GET("/getWebsites", ginx.Wrap(h.GetWebsites))
-	sg.POST("/saveWebsite", authMiddleware, ginx.WrapClaimsAndReq(h.SaveWebsite))
-	sg.DELETE("/delWebsite", authMiddleware, ginx.WrapClaimsAndReq(h.DelWebsite))
+	sg.POST("/saveWebsite", authMiddleware, h.Authorizer.RequirePermission(perm.WebsiteWrite), ginx.WrapClaimsAndReq(h.SaveWebsite))
+	sg.DELETE("/delWebsite", authMiddleware, h.Authorizer.RequirePermission(perm.WebsiteWrite), ginx.WrapClaimsAndReq(h.DelWebsite))
+}
+
+// AuthPolicies 网站列表游客也能看,增删可以用 API Key 调用
+func (h *WebsiteHandler) AuthPolicies() []web.RoutePolicy {
+	return []web.RoutePolicy{
+		{Method: http.MethodGet, Path: "/website/getWebsites", Auth: web.AuthPublic},
+		{Method: http.MethodPost, Path: "/website/saveWebsite", Auth: web.AuthRequired, Service: true},
+		{Method: http.MethodDelete, Path: "/website/delWebsite", Auth: web.AuthRequired, Service: true},
+	}
 }
 
 // GetWebsites 获取网站列表
@@ -62,9 +72,6 @@ func (h *WebsiteHandler) GetWebsites(ctx *gin.Context) (web.Response, error) {
 // @Success 200 {object} web.Response "成功"
 // @Router /website/saveWebsite [post]
 func (h *WebsiteHandler) SaveWebsite(ctx *gin.Context, req SaveWebsiteRequest, uc ijwt.UserClaims) (web.Response, error) {
-	if !h.isAdmin(uc.StudentId) {
-		return web.Response{}, errs.ROLE_ERROR(fmt.Errorf("没有访问权限: %s", uc.StudentId))
-	}
 	_, err := h.websiteClient.SaveWebsite(ctx, &websitev1.SaveWebsiteRequest{
 		Website: &websitev1.Website{
 			Id:          req.Id,
@@ -92,10 +99,6 @@ func (h *WebsiteHandler) SaveWebsite(ctx *gin.Context, req SaveWebsiteRequest, u
 // @Success 200 {object} web.Response "成功"
 // @Router /website/delWebsite [delete]
 func (h *WebsiteHandler) DelWebsite(ctx *gin.Context, req department.DelDepartmentRequest, uc ijwt.UserClaims) (web.Response, error) {
-	if !h.isAdmin(uc.StudentId) {
-		return web.Response{}, errs.ROLE_ERROR(fmt.Errorf("没有访问权限: %s", uc.StudentId))
-	}
-
 	_, err := h.websiteClient.DelWebsite(ctx, &websitev1.DelWebsiteRequest{Id: req.Id})
 	if err != nil {
 		return web.Response{}, errs.DEL_WEBSITE_ERROR(err)
@@ -104,8 +107,3 @@ func (h *WebsiteHandler) DelWebsite(ctx *gin.Context, req department.DelDepartme
 		Msg: "Success",
 	}, nil
 }
-
-func (h *WebsiteHandler) isAdmin(studentId string) bool {
-	_, exists := h.Administrators[studentId]
-	return exists
-}
@@ -0,0 +1,38 @@
+package wellknown
+
+import (
+	"github.com/asynccnu/bff/pkg/jwtx"
+	"github.com/gin-gonic/gin"
+	"net/http"
+)
+
+// KeyProvider 提供可以公开的验签公钥
+type KeyProvider interface {
+	JWKS() jwtx.JWKS
+}
+
+// WellKnownHandler 挂在根路径下的 /.well-known/ 标准路由,和健康检查一样不走 /api/v1 的中间件
+type WellKnownHandler struct {
+	keys KeyProvider
+}
+
+func NewWellKnownHandler(keys KeyProvider) *WellKnownHandler {
+	return &WellKnownHandler{keys: keys}
+}
+
+func (h *WellKnownHandler) RegisterRoutes(s *gin.RouterGroup, authMiddleware gin.HandlerFunc) {
+	s.GET("/.well-known/jwks.json", h.JWKS)
+}
+
+// JWKS 公开 JWT 的验签公钥
+// @Summary JWT 验签公钥
+// @Description 按 RFC 7517 返回当前有效的所有公钥,其它服务按 token header 里的 kid 选择公钥验签,HS256 模式下返回空列表
+// @Tags well-known
+// @Produce json
+// @Success 200 {object} jwtx.JWKS "成功"
+// @Router /.well-known/jwks.json [get]
+func (h *WellKnownHandler) JWKS(ctx *gin.Context) {
+	// 公钥只会随着发版轮换,允许缓存一会儿
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, h.keys.JWKS())
+}
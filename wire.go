@@ -4,19 +4,32 @@
 package main
 
 import (
+	"github.com/asynccnu/bff/config"
 	"github.com/asynccnu/bff/ioc"
+	"github.com/asynccnu/bff/web/apikey"
+	"github.com/asynccnu/bff/web/loginguard"
 	"github.com/asynccnu/bff/web/middleware"
 	"github.com/google/wire"
 )
 
-func InitApp() *App {
+func InitApp(cfg *config.Config) (*App, func()) {
 	wire.Build(
 		// 组件
+		ioc.InitRuntimeConfig,
+		ioc.InitConfigReloader,
 		ioc.InitPrometheus,
 		ioc.InitEtcdClient,
 		ioc.InitLogger,
 		ioc.InitRedis,
+		ioc.InitHealthRegistry,
+		loginguard.NewLoginGuard,
+		ioc.InitRBAC,
+		apikey.NewService,
+		ioc.InitAuditSink,
+		ioc.InitAuditRecorder,
+		ioc.InitAuditor,
 		//grpc注册
+		ioc.InitGrpcClientFactory,
 		ioc.InitDepartmentClient,
 		ioc.InitWebsiteClient,
 		ioc.InitBannerClient,
@@ -54,14 +67,24 @@ func InitApp() *App {
 		ioc.InitInfoSumHandler,
 		ioc.InitCardHandler,
 		ioc.InitMetricsHandel,
+		ioc.InitHealthHandler,
+		ioc.InitAdminHandler,
+		ioc.InitWellKnownHandler,
+		ioc.InitRBACHandler,
+		ioc.InitApiKeyHandler,
+		ioc.InitAuditHandler,
 
 		//中间件
 		middleware.NewLoggerMiddleware,
 		middleware.NewCorsMiddleware,
+		middleware.NewRateLimitMiddleware,
+		middleware.NewDeadlineMiddleware,
 		middleware.NewLoginMiddleWare,
+		ioc.InitApiKeyAuthenticator,
+		ioc.InitPermissionMiddleware,
 		//注册api
 		ioc.InitGinServer,
 		NewApp,
 	)
-	return &App{}
+	return &App{}, nil
 }
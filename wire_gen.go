@@ -7,55 +7,99 @@
 package main
 
 import (
+	"github.com/asynccnu/bff/config"
 	"github.com/asynccnu/bff/ioc"
+	"github.com/asynccnu/bff/web/apikey"
+	"github.com/asynccnu/bff/web/loginguard"
 	"github.com/asynccnu/bff/web/middleware"
 )
 
 // Injectors from wire.go:
 
-func InitApp() *App {
-	logger := ioc.InitLogger()
-	prometheusCounter := ioc.InitPrometheus()
+func InitApp(cfg *config.Config) (*App, func()) {
+	runtime := ioc.InitRuntimeConfig(cfg)
+	logger, cleanup := ioc.InitLogger(cfg, runtime)
+	prometheusCounter := ioc.InitPrometheus(cfg)
 	loggerMiddleware := middleware.NewLoggerMiddleware(logger, prometheusCounter)
-	cmdable := ioc.InitRedis()
-	handler := ioc.InitJwtHandler(cmdable)
-	loginMiddleware := middleware.NewLoginMiddleWare(handler)
-	corsMiddleware := middleware.NewCorsMiddleware()
-	putPolicy := ioc.InitPutPolicy()
-	credentials := ioc.InitMac()
-	tubeHandler := ioc.InitTubeHandler(putPolicy, credentials)
-	client := ioc.InitEtcdClient()
-	userServiceClient := ioc.InitUserClient(client)
-	ccnuServiceClient := ioc.InitCCNUClient(client)
-	userHandler := ioc.InitUserHandler(handler, userServiceClient, ccnuServiceClient)
-	staticServiceClient := ioc.InitStaticClient(client)
-	staticHandler := ioc.InitStaticHandler(staticServiceClient)
-	bannerServiceClient := ioc.InitBannerClient(client)
-	bannerHandler := ioc.InitBannerHandler(bannerServiceClient)
-	departmentServiceClient := ioc.InitDepartmentClient(client)
-	departmentHandler := ioc.InitDepartmentHandler(departmentServiceClient)
-	websiteServiceClient := ioc.InitWebsiteClient(client)
-	websiteHandler := ioc.InitWebsiteHandler(websiteServiceClient)
-	calendarServiceClient := ioc.InitCalendarClient(client)
-	calendarHandler := ioc.InitCalendarHandler(calendarServiceClient)
-	feedServiceClient := ioc.InitFeedClient(client)
-	feedHandler := ioc.InitFeedHandler(feedServiceClient)
-	elecpriceServiceClient := ioc.InitElecpriceClient(client)
+	cmdable, cleanup2 := ioc.InitRedis(cfg)
+	handler := ioc.InitJwtHandler(cfg, cmdable, logger)
+	service := apikey.NewService(cmdable)
+	apiKeyAuthenticator := ioc.InitApiKeyAuthenticator(service)
+	sink, cleanup3 := ioc.InitAuditSink(cfg, cmdable)
+	recorder := ioc.InitAuditRecorder(sink, logger)
+	auditor := ioc.InitAuditor(recorder)
+	loginMiddleware := middleware.NewLoginMiddleWare(handler, apiKeyAuthenticator, auditor, runtime, logger, prometheusCounter)
+	corsMiddleware := middleware.NewCorsMiddleware(runtime)
+	rateLimitMiddleware := middleware.NewRateLimitMiddleware(cmdable, runtime, logger)
+	deadlineMiddleware := middleware.NewDeadlineMiddleware(cfg)
+	putPolicy := ioc.InitPutPolicy(cfg)
+	v := ioc.InitMac(cfg)
+	tubeHandler := ioc.InitTubeHandler(cfg, putPolicy, v)
+	loginGuard := loginguard.NewLoginGuard(cmdable, runtime, logger)
+	rbac := ioc.InitRBAC(cfg, cmdable, runtime, logger)
+	registry := ioc.InitHealthRegistry(cfg, cmdable)
+	etcdClient, cleanup4 := ioc.InitEtcdClient(cfg, registry)
+	grpcClientFactory := ioc.InitGrpcClientFactory(cfg, etcdClient, registry, logger)
+	userServiceClient, cleanup5 := ioc.InitUserClient(grpcClientFactory)
+	ccnuServiceClient, cleanup6 := ioc.InitCCNUClient(grpcClientFactory)
+	permissionMiddleware := ioc.InitPermissionMiddleware(rbac, recorder)
+	userHandler := ioc.InitUserHandler(cfg, handler, loginGuard, rbac, userServiceClient, ccnuServiceClient, permissionMiddleware)
+	staticServiceClient, cleanup7 := ioc.InitStaticClient(grpcClientFactory)
+	staticHandler := ioc.InitStaticHandler(staticServiceClient, permissionMiddleware)
+	bannerServiceClient, cleanup8 := ioc.InitBannerClient(grpcClientFactory)
+	bannerHandler := ioc.InitBannerHandler(bannerServiceClient, permissionMiddleware)
+	departmentServiceClient, cleanup9 := ioc.InitDepartmentClient(grpcClientFactory)
+	departmentHandler := ioc.InitDepartmentHandler(departmentServiceClient, permissionMiddleware)
+	websiteServiceClient, cleanup10 := ioc.InitWebsiteClient(grpcClientFactory)
+	websiteHandler := ioc.InitWebsiteHandler(websiteServiceClient, permissionMiddleware)
+	calendarServiceClient, cleanup11 := ioc.InitCalendarClient(grpcClientFactory)
+	calendarHandler := ioc.InitCalendarHandler(calendarServiceClient, permissionMiddleware)
+	feedServiceClient, cleanup12 := ioc.InitFeedClient(grpcClientFactory)
+	feedHandler := ioc.InitFeedHandler(feedServiceClient, permissionMiddleware)
+	elecpriceServiceClient, cleanup13 := ioc.InitElecpriceClient(grpcClientFactory)
 	elecPriceHandler := ioc.InitElecpriceHandler(elecpriceServiceClient)
-	gradeServiceClient := ioc.InitGradeClient(client)
-	counterServiceClient := ioc.InitCounterClient(client)
+	gradeServiceClient, cleanup14 := ioc.InitGradeClient(grpcClientFactory)
+	counterServiceClient, cleanup15 := ioc.InitCounterClient(grpcClientFactory)
 	gradeHandler := ioc.InitGradeHandler(logger, gradeServiceClient, counterServiceClient)
-	classerClient := ioc.InitClassList(client)
-	classServiceClient := ioc.InitClassService(client)
+	classerClient, cleanup16 := ioc.InitClassList(grpcClientFactory)
+	classServiceClient, cleanup17 := ioc.InitClassService(grpcClientFactory)
 	classHandler := ioc.InitClassHandler(classerClient, classServiceClient)
-	feedbackHelpClient := ioc.InitFeedbackHelpClient(client)
-	feedbackHelpHandler := ioc.InitFeedbackHelpHandler(feedbackHelpClient)
-	infoSumServiceClient := ioc.InitInfoSumClient(client)
-	infoSumHandler := ioc.InitInfoSumHandler(infoSumServiceClient)
-	cardClient := ioc.InitCardClient(client)
+	feedbackHelpClient, cleanup18 := ioc.InitFeedbackHelpClient(grpcClientFactory)
+	feedbackHelpHandler := ioc.InitFeedbackHelpHandler(feedbackHelpClient, permissionMiddleware)
+	infoSumServiceClient, cleanup19 := ioc.InitInfoSumClient(grpcClientFactory)
+	infoSumHandler := ioc.InitInfoSumHandler(infoSumServiceClient, permissionMiddleware)
+	cardClient, cleanup20 := ioc.InitCardClient(grpcClientFactory)
 	cardHandler := ioc.InitCardHandler(cardClient)
 	metricsHandler := ioc.InitMetricsHandel()
-	engine := ioc.InitGinServer(loggerMiddleware, loginMiddleware, corsMiddleware, tubeHandler, userHandler, staticHandler, bannerHandler, departmentHandler, websiteHandler, calendarHandler, feedHandler, elecPriceHandler, gradeHandler, classHandler, feedbackHelpHandler, infoSumHandler, cardHandler, metricsHandler)
-	app := NewApp(engine)
-	return app
+	healthHandler := ioc.InitHealthHandler(registry)
+	adminHandler := ioc.InitAdminHandler(cfg, runtime, grpcClientFactory, loginGuard, permissionMiddleware)
+	wellKnownHandler := ioc.InitWellKnownHandler(handler)
+	rbacHandler := ioc.InitRBACHandler(rbac, permissionMiddleware)
+	apiKeyHandler := ioc.InitApiKeyHandler(service, permissionMiddleware)
+	auditHandler := ioc.InitAuditHandler(sink, permissionMiddleware)
+	engine := ioc.InitGinServer(cfg, loggerMiddleware, loginMiddleware, corsMiddleware, rateLimitMiddleware, deadlineMiddleware, tubeHandler, userHandler, staticHandler, bannerHandler, departmentHandler, websiteHandler, calendarHandler, feedHandler, elecPriceHandler, gradeHandler, classHandler, feedbackHelpHandler, infoSumHandler, cardHandler, metricsHandler, healthHandler, registry, adminHandler, wellKnownHandler, rbacHandler, apiKeyHandler, auditHandler)
+	reloader := ioc.InitConfigReloader(runtime, logger, prometheusCounter)
+	app := NewApp(cfg, engine, logger, reloader)
+	return app, func() {
+		cleanup20()
+		cleanup19()
+		cleanup18()
+		cleanup17()
+		cleanup16()
+		cleanup15()
+		cleanup14()
+		cleanup13()
+		cleanup12()
+		cleanup11()
+		cleanup10()
+		cleanup9()
+		cleanup8()
+		cleanup7()
+		cleanup6()
+		cleanup5()
+		cleanup4()
+		cleanup3()
+		cleanup2()
+		cleanup()
+	}
 }